@@ -0,0 +1,79 @@
+// yopay project client.go
+package yopay
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// shared client used when YoAPI.HTTPClient is nil, keeps connections alive between calls
+var defaultHTTPClient, _ = NewHTTPClient(nil)
+
+/* NewHTTPClient
+Create http client for YoAPI.HTTPClient which verifies the gateway TLS certificate
+* roots the CA pool used to verify the gateway certificate, nil for the system pool
+* pins optional SHA-256 fingerprints (hex, colons allowed) of accepted certificates,
+  the connection is refused unless one of the certificates presented by the gateway matches.
+  With pins and nil roots the pin replaces the CA verification and must match the gateway's own
+  certificate, for gateways like the sandbox whose certificate no CA vouches for
+*/
+func NewHTTPClient(roots *x509.CertPool, pins ...string) (*http.Client, error) {
+	var fingerprints [][]byte
+	for _, pin := range pins {
+		fp, err := hex.DecodeString(strings.ReplaceAll(pin, ":", ""))
+		if err != nil || len(fp) != sha256.Size {
+			return nil, fmt.Errorf("invalid certificate fingerprint %q", pin)
+		}
+		fingerprints = append(fingerprints, fp)
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    roots,
+		MinVersion: tls.VersionTLS12,
+	}
+	switch {
+	case len(fingerprints) > 0 && roots == nil:
+		// the chain is not verified, so only the leaf may match
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("gateway presented no certificate")
+			}
+			return verifyPinnedCertificate(cs.PeerCertificates[:1], fingerprints)
+		}
+	case len(fingerprints) > 0:
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPinnedCertificate(cs.PeerCertificates, fingerprints)
+		}
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: tr}, nil
+}
+
+func verifyPinnedCertificate(certs []*x509.Certificate, fingerprints [][]byte) error {
+	for _, cert := range certs {
+		sum := sha256.Sum256(cert.Raw)
+		for _, fp := range fingerprints {
+			if bytes.Equal(sum[:], fp) {
+				return nil
+			}
+		}
+	}
+	return errors.New("gateway certificate does not match any pinned fingerprint")
+}
+
+func (api *YoAPI) httpClient() *http.Client {
+	if api.HTTPClient != nil {
+		return api.HTTPClient
+	}
+	return defaultHTTPClient
+}
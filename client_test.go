@@ -0,0 +1,61 @@
+package yopay
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestingApi never reaches a gateway, tests set YoUrl to their own server
+func newTestingApi(t *testing.T) *YoAPI {
+	result := NewYoApi("user", "password")
+	result.YoUrl = "http://127.0.0.1:1/unreachable"
+	return &result
+}
+
+func TestHTTPClientVerifiesCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>OK</Status><StatusCode>0</StatusCode></Response></AutoCreate>`))
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+
+	if _, err := yo.GetAcctBalance(); err == nil {
+		t.Fatalf("self signed certificate must be rejected by default")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	sum := sha256.Sum256(srv.Certificate().Raw)
+
+	yo.HTTPClient, _ = NewHTTPClient(roots, hex.EncodeToString(sum[:]))
+	if _, err := yo.GetAcctBalance(); err != nil {
+		t.Fatalf("pinned certificate rejected: %v", err)
+	}
+
+	sum[0]++
+	yo.HTTPClient, _ = NewHTTPClient(roots, hex.EncodeToString(sum[:]))
+	if _, err := yo.GetAcctBalance(); err == nil {
+		t.Fatalf("certificate with wrong fingerprint accepted")
+	}
+
+	// pin without roots stands in for the CA
+	sum[0]--
+	yo.HTTPClient, _ = NewHTTPClient(nil, hex.EncodeToString(sum[:]))
+	if _, err := yo.GetAcctBalance(); err != nil {
+		t.Fatalf("pinned certificate without roots rejected: %v", err)
+	}
+	sum[0]++
+	yo.HTTPClient, _ = NewHTTPClient(nil, hex.EncodeToString(sum[:]))
+	if _, err := yo.GetAcctBalance(); err == nil {
+		t.Fatalf("certificate with wrong fingerprint accepted without roots")
+	}
+
+	if _, err := NewHTTPClient(nil, "zz"); err == nil {
+		t.Fatalf("invalid fingerprint accepted")
+	}
+}
@@ -0,0 +1,126 @@
+// yopay project currency.go
+package yopay
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+/* CurrencyCode
+Yo! account currency, the ISO currency and the network joined by a dash
+*/
+type CurrencyCode string
+
+const (
+	UGXMTNMobileMoney    CurrencyCode = "UGX-MTNMM"
+	UGXAirtelMobileMoney CurrencyCode = "UGX-WARIDMM"
+	UGXMTNAirtime        CurrencyCode = "UGX-MTNAT"
+	UGXWaridAirtime      CurrencyCode = "UGX-WTLAT"
+	UGXOrangeAirtime     CurrencyCode = "UGX-OULAT"
+	UGXAirtelAirtime     CurrencyCode = "UGX-AIRAT"
+)
+
+// CurrencyKind tells mobile money accounts from airtime accounts
+type CurrencyKind int
+
+const (
+	MobileMoney CurrencyKind = iota
+	Airtime
+)
+
+func (k CurrencyKind) String() string {
+	if k == Airtime {
+		return "airtime"
+	}
+	return "mobile money"
+}
+
+/* CurrencyInfo
+Metadata of a currency code
+*/
+type CurrencyInfo struct {
+	Code CurrencyCode
+	// ISO 4217 code e.g. "UGX"
+	ISOCurrency string
+	Network     Network
+	Kind        CurrencyKind
+	Description string
+}
+
+// currencies are the codes accepted by the methods
+var currencies = map[CurrencyCode]CurrencyInfo{
+	UGXMTNMobileMoney:    {UGXMTNMobileMoney, "UGX", NetworkMTN, MobileMoney, "Uganda Shillings - MTN Mobile Money"},
+	UGXAirtelMobileMoney: {UGXAirtelMobileMoney, "UGX", NetworkAirtel, MobileMoney, "Uganda Shillings - Airtel Money"},
+	UGXMTNAirtime:        {UGXMTNAirtime, "UGX", NetworkMTN, Airtime, "Uganda Shillings - MTN Airtime"},
+	UGXWaridAirtime:      {UGXWaridAirtime, "UGX", NetworkWarid, Airtime, "Uganda Shillings - Warid Airtime"},
+	UGXOrangeAirtime:     {UGXOrangeAirtime, "UGX", NetworkOrange, Airtime, "Uganda Shillings - Orange Airtime"},
+	UGXAirtelAirtime:     {UGXAirtelAirtime, "UGX", NetworkAirtel, Airtime, "Uganda Shillings - Airtel Airtime"},
+}
+
+var ErrInvalidCurrency = errors.New("yopay: invalid currency code")
+
+/* Currencies
+Currency codes accepted by the methods, sorted by code
+*/
+func Currencies() []CurrencyInfo {
+	result := make([]CurrencyInfo, 0, len(currencies))
+	for _, info := range currencies {
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Code < result[j].Code })
+	return result
+}
+
+/* Info
+Metadata of c, false when c is not in Currencies
+*/
+func (c CurrencyCode) Info() (CurrencyInfo, bool) {
+	info, ok := currencies[c]
+	return info, ok
+}
+
+// Valid reports whether c is in Currencies
+func (c CurrencyCode) Valid() bool {
+	_, ok := currencies[c]
+	return ok
+}
+
+// validateCurrency checks that c is known and, when kinds are given, of one of them
+func validateCurrency(c CurrencyCode, kinds ...CurrencyKind) error {
+	info, ok := c.Info()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidCurrency, c)
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+	for _, kind := range kinds {
+		if info.Kind == kind {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is %s", ErrInvalidCurrency, c, info.Kind)
+}
+
+/* Info
+Metadata of the balance currency, false when the code is not in Currencies
+*/
+func (b CurrencyBalance) Info() (CurrencyInfo, bool) {
+	return b.Code.Info()
+}
+
+/* Balances
+Balances by currency code, including codes missing from Currencies
+*/
+func (r BalanceResponse) Balances() (map[CurrencyCode]Money, error) {
+	result := make(map[CurrencyCode]Money, len(r.Balance.Currency))
+	for _, c := range r.Balance.Currency {
+		m, err := c.Money()
+		if err != nil {
+			return nil, fmt.Errorf("balance of %s: %w", c.Code, err)
+		}
+		result[c.Code] = m
+	}
+	return result, nil
+}
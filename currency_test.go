@@ -0,0 +1,55 @@
+package yopay
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"testing"
+)
+
+func TestCurrencyValidation(t *testing.T) {
+	yo := newTestingApi(t)
+	ctx := context.Background()
+	if _, err := yo.InternalTransfer("UGX-MTMM", 10, "100200", "someone@example.com", "typo"); !errors.Is(err, ErrInvalidCurrency) {
+		t.Fatalf("unknown currency accepted: %v", err)
+	}
+	if _, err := yo.SendAirtimeInternalContext(ctx, SendAirtimeInternalRequest{CurrencyCode: UGXMTNMobileMoney, Amount: Money{Minor: 1000}}); !errors.Is(err, ErrInvalidCurrency) {
+		t.Fatalf("mobile money sent as airtime: %v", err)
+	}
+	if _, err := yo.WithdrawFundsContext(ctx, WithdrawFundsRequest{Account: "256771234567", Amount: Money{Minor: 1000, Currency: UGXAirtelAirtime}}); !errors.Is(err, ErrInvalidCurrency) {
+		t.Fatalf("airtime withdrawn: %v", err)
+	}
+	if _, err := yo.GetMinistatementContext(ctx, MinistatementRequest{CurrencyCode: "UGX"}); !errors.Is(err, ErrInvalidCurrency) {
+		t.Fatalf("unknown statement currency accepted: %v", err)
+	}
+}
+
+func TestBalanceCurrencies(t *testing.T) {
+	var r BalanceResponse
+	data := `<Response><Balance><Currency><Code>UGX-MTNMM</Code><Balance>1500.50</Balance></Currency>` +
+		`<Currency><Code>UGX-NEWMM</Code><Balance>0</Balance></Currency></Balance></Response>`
+	if err := xml.Unmarshal([]byte(data), &r); err != nil {
+		t.Fatal(err)
+	}
+	if info, ok := r.Balance.Currency[0].Info(); !ok || info.Network != "MTN" || info.Kind != MobileMoney || info.ISOCurrency != "UGX" {
+		t.Fatalf("info %+v", info)
+	}
+	if _, ok := r.Balance.Currency[1].Info(); ok {
+		t.Fatal("unknown code mapped")
+	}
+	balances, err := r.Balances()
+	if err != nil || balances[UGXMTNMobileMoney].Minor != 150050 || len(balances) != 2 {
+		t.Fatalf("balances %v %v", balances, err)
+	}
+}
+
+func TestCurrenciesCatalog(t *testing.T) {
+	list := Currencies()
+	if len(list) != 6 || list[0].Code != UGXAirtelAirtime {
+		t.Fatalf("catalog %+v", list)
+	}
+	list[0].Code = "UGX-CHANGED"
+	if !UGXAirtelAirtime.Valid() || CurrencyCode("UGX-CHANGED").Valid() {
+		t.Fatal("catalog modified through Currencies")
+	}
+}
@@ -0,0 +1,96 @@
+// yopay project endpoints.go
+package yopay
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	PaymentsAPI1 = "https://paymentsapi1.yo.co.ug/ybs/task.php"
+	PaymentsAPI2 = "https://paymentsapi2.yo.co.ug/ybs/task.php"
+)
+
+// ErrNoEndpoints is returned without sending anything when the EndpointSet has no urls
+var ErrNoEndpoints = errors.New("yopay: no gateway endpoints")
+
+// methods which do not move money and may be resent to another endpoint
+var retryableMethods = map[string]bool{
+	"acacctbalance":            true,
+	"actransactioncheckstatus": true,
+	"acgetministatement":       true,
+	"acverifyaccountvalidity":  true,
+}
+
+/* EndpointSet
+Gateway urls with health tracking, safe for concurrent use
+*/
+type EndpointSet struct {
+	/* Cooldown
+	   How long a failed endpoint is moved behind the healthy ones
+	   Default: 30 seconds
+	*/
+	Cooldown time.Duration
+
+	mu       sync.Mutex
+	urls     []string
+	failedAt map[string]time.Time
+}
+
+/* NewEndpointSet
+Create endpoint set, urls are tried in the given order
+*/
+func NewEndpointSet(urls ...string) *EndpointSet {
+	return &EndpointSet{
+		Cooldown: 30 * time.Second,
+		urls:     urls,
+		failedAt: make(map[string]time.Time),
+	}
+}
+
+/* Healthy
+Report whether url did not fail within Cooldown
+*/
+func (s *EndpointSet) Healthy(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.healthy(url, time.Now())
+}
+
+func (s *EndpointSet) healthy(url string, now time.Time) bool {
+	failed, ok := s.failedAt[url]
+	return !ok || now.Sub(failed) >= s.Cooldown
+}
+
+// order returns the healthy urls first, then the failed ones starting from the oldest failure
+func (s *EndpointSet) order() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var healthy, failed []string
+	for _, url := range s.urls {
+		if s.healthy(url, now) {
+			healthy = append(healthy, url)
+			continue
+		}
+		i := len(failed)
+		for i > 0 && s.failedAt[failed[i-1]].After(s.failedAt[url]) {
+			i--
+		}
+		failed = append(failed[:i], append([]string{url}, failed[i:]...)...)
+	}
+	return append(healthy, failed...)
+}
+
+func (s *EndpointSet) markFailed(url string) {
+	s.mu.Lock()
+	s.failedAt[url] = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *EndpointSet) markHealthy(url string) {
+	s.mu.Lock()
+	delete(s.failedAt, url)
+	s.mu.Unlock()
+}
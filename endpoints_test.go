@@ -0,0 +1,62 @@
+package yopay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEndpointFailover(t *testing.T) {
+	var downHits, upHits int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downHits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upHits, 1)
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>OK</Status><StatusCode>0</StatusCode></Response></AutoCreate>`))
+	}))
+	defer up.Close()
+
+	yo := newTestingApi(t)
+	yo.Endpoints = NewEndpointSet(down.URL, up.URL)
+	if _, err := yo.WithdrawFunds("256771234567", 100, "test"); err == nil {
+		t.Fatalf("withdrawal must fail on the broken endpoint")
+	}
+	if downHits != 1 || upHits != 0 {
+		t.Fatalf("withdrawal was resent: down %d, up %d", downHits, upHits)
+	}
+	if yo.Endpoints.Healthy(down.URL) {
+		t.Fatalf("failed endpoint still healthy")
+	}
+
+	if _, err := yo.WithdrawFunds("256771234567", 100, "test"); err != nil {
+		t.Fatalf("withdrawal not routed to the healthy endpoint: %v", err)
+	}
+
+	yo.Endpoints = NewEndpointSet(down.URL, up.URL)
+	if _, err := yo.GetAcctBalance(); err != nil {
+		t.Fatalf("balance query did not fail over: %v", err)
+	}
+	if downHits != 2 || upHits != 2 {
+		t.Fatalf("unexpected hits: down %d, up %d", downHits, upHits)
+	}
+}
+
+func TestEmptyEndpointSet(t *testing.T) {
+	yo := newTestingApi(t)
+	yo.Endpoints = NewEndpointSet()
+	yo.ResolveTimeouts = true
+	yo.Observer = func(e Exchange) { t.Fatalf("request sent to %s", e.Url) }
+	if _, err := yo.GetAcctBalance(); !errors.Is(err, ErrNoEndpoints) {
+		t.Fatalf("expected ErrNoEndpoints, got %v", err)
+	}
+	_, err := yo.WithdrawFundsContext(context.Background(), WithdrawFundsRequest{Account: "256771234567", Amount: Money{Minor: 10000}, Narrative: "test", ExternalReference: "ref-1"})
+	if !errors.Is(err, ErrNoEndpoints) || errors.Is(err, ErrOutcomeUnknown) {
+		t.Fatalf("expected ErrNoEndpoints without resolution, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// yopay project errors.go
+package yopay
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInsufficientBalance = errors.New("yopay: insufficient balance")
+	ErrInvalidAccount      = errors.New("yopay: invalid account")
+	ErrAuthFailed          = errors.New("yopay: authentication failed")
+	ErrDuplicateReference  = errors.New("yopay: duplicate reference")
+)
+
+// statusCodeErrors maps the error StatusCode values listed in the "Status Codes" appendix of the
+// Yo! Payments Business API specification to the sentinel errors. Only StatusCode is matched,
+// ErrorMessageCode is free text
+var statusCodeErrors = map[string]error{
+	"-3":  ErrAuthFailed,
+	"-18": ErrInvalidAccount,
+	"-22": ErrInsufficientBalance,
+	"-33": ErrDuplicateReference,
+}
+
+/* YoError
+Returned by the gateway methods when the response Status is "ERROR",
+the decoded response is returned along with it
+*/
+type YoError struct {
+	Status           string
+	StatusCode       string
+	StatusMessage    string
+	ErrorMessageCode string
+	ErrorMessage     string
+}
+
+func (e *YoError) Error() string {
+	msg := e.ErrorMessage
+	if len(msg) == 0 {
+		msg = e.StatusMessage
+	}
+	if len(e.ErrorMessageCode) > 0 {
+		return fmt.Sprintf("yopay: gateway error %s (%s): %s", e.StatusCode, e.ErrorMessageCode, msg)
+	}
+	return fmt.Sprintf("yopay: gateway error %s: %s", e.StatusCode, msg)
+}
+
+func (e *YoError) Is(target error) bool {
+	sentinel, ok := statusCodeErrors[e.StatusCode]
+	return ok && sentinel == target
+}
+
+// newYoError returns nil unless status reports an error
+func newYoError(status, statusCode, statusMessage, errorMessageCode, errorMessage string) error {
+	if status != "ERROR" {
+		return nil
+	}
+	return &YoError{
+		Status:           status,
+		StatusCode:       statusCode,
+		StatusMessage:    statusMessage,
+		ErrorMessageCode: errorMessageCode,
+		ErrorMessage:     errorMessage,
+	}
+}
+
+func (r DepositResponse) yoError() error {
+	return newYoError(r.Status, r.StatusCode, r.StatusMessage, r.ErrorMessageCode, r.ErrorMessage)
+}
+
+func (r BalanceResponse) yoError() error {
+	return newYoError(r.Status, r.StatusCode, r.StatusMessage, r.ErrorMessageCode, r.ErrorMessage)
+}
+
+func (r MinistatementResponse) yoError() error {
+	return newYoError(r.Status, r.StatusCode, r.StatusMessage, r.ErrorMessageCode, r.ErrorMessage)
+}
+
+func (r VerifyAccountResponse) yoError() error {
+	return newYoError(r.Status, r.StatusCode, r.StatusMessage, r.ErrorMessageCode, r.ErrorMessage)
+}
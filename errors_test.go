@@ -0,0 +1,41 @@
+package yopay
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGatewayErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>ERROR</Status><StatusCode>-22</StatusCode>` +
+			`<StatusMessage>Insufficient balance</StatusMessage>` +
+			`<ErrorMessage>Your balance is too low</ErrorMessage></Response></AutoCreate>`))
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+
+	r, err := yo.WithdrawFunds("256771234567", 100, "test")
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected insufficient balance, got %v", err)
+	}
+	if errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("error matches unrelated sentinel")
+	}
+	var yoerr *YoError
+	if !errors.As(err, &yoerr) || yoerr.ErrorMessage != "Your balance is too low" || yoerr.StatusMessage != "Insufficient balance" {
+		t.Fatalf("unexpected error %#v", err)
+	}
+	if r.StatusCode != "-22" {
+		t.Fatalf("response not returned with the error: %+v", r)
+	}
+
+	if _, err := yo.GetAcctBalance(); !errors.As(err, &yoerr) {
+		t.Fatalf("balance error not reported: %v", err)
+	}
+	if valid, err := yo.VerifyAccountValidity("256771234567"); valid || !errors.As(err, &yoerr) {
+		t.Fatalf("verify account error not reported: %v %v", valid, err)
+	}
+}
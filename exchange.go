@@ -0,0 +1,29 @@
+// yopay project exchange.go
+package yopay
+
+import (
+	"regexp"
+	"time"
+)
+
+/* Exchange
+One round trip with the gateway, reported to YoAPI.Observer
+*/
+type Exchange struct {
+	// gateway method e.g. "acdepositfunds", empty for GetXmlResponse
+	Method string
+	Url    string
+	// request body with APIUsername and APIPassword replaced by "***"
+	Request  string
+	Response string
+	// http status, 0 when no response was received
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+var credentialsRe = regexp.MustCompile(`<(APIUsername|APIPassword)>[^<]*</(APIUsername|APIPassword)>`)
+
+func redactCredentials(xmlbody string) string {
+	return credentialsRe.ReplaceAllString(xmlbody, "<$1>***</$2>")
+}
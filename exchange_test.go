@@ -0,0 +1,37 @@
+package yopay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestObserverRedactsCredentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>OK</Status><StatusCode>0</StatusCode></Response></AutoCreate>`))
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+	var exchanges []Exchange
+	yo.Observer = func(e Exchange) {
+		exchanges = append(exchanges, e)
+	}
+	if _, err := yo.GetAcctBalance(); err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 1 {
+		t.Fatalf("expected one exchange, got %d", len(exchanges))
+	}
+	e := exchanges[0]
+	if e.Method != "acacctbalance" || e.StatusCode != 200 || e.Err != nil || !strings.Contains(e.Response, "<Status>OK</Status>") {
+		t.Fatalf("unexpected exchange %+v", e)
+	}
+	if strings.Contains(e.Request, yo.Username) || strings.Contains(e.Request, yo.Password) {
+		t.Fatalf("credentials leaked: %s", e.Request)
+	}
+	if !strings.Contains(e.Request, "<APIPassword>***</APIPassword>") {
+		t.Fatalf("password not redacted: %s", e.Request)
+	}
+}
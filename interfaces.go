@@ -0,0 +1,92 @@
+// yopay project interfaces.go
+package yopay
+
+import (
+	"context"
+)
+
+// The interfaces take the Context forms of the gateway methods, the methods without
+// Context suffix are shorthands filling the requests from the YoAPI fields.
+
+/* Depositor
+Pulls funds from mobile money accounts, see DepositFundsContext
+*/
+type Depositor interface {
+	DepositFundsContext(ctx context.Context, req DepositFundsRequest) (DepositResponse, error)
+}
+
+/* Withdrawer
+Pays funds out to mobile money accounts, see WithdrawFundsContext
+*/
+type Withdrawer interface {
+	WithdrawFundsContext(ctx context.Context, req WithdrawFundsRequest) (DepositResponse, error)
+}
+
+/* TransactionChecker
+Queries the state of a transaction, see CheckTransactionStatusContext
+*/
+type TransactionChecker interface {
+	CheckTransactionStatusContext(ctx context.Context, req TransactionCheckStatusRequest) (TransactionStatus, error)
+}
+
+/* Transferrer
+Moves funds to other Yo! Payments accounts, see InternalTransferContext
+*/
+type Transferrer interface {
+	InternalTransferContext(ctx context.Context, req InternalTransferRequest) (DepositResponse, error)
+}
+
+/* AirtimeSender
+Sends airtime, see SendAirtimeMobileContext and SendAirtimeInternalContext
+*/
+type AirtimeSender interface {
+	SendAirtimeMobileContext(ctx context.Context, req SendAirtimeMobileRequest) (DepositResponse, error)
+	SendAirtimeInternalContext(ctx context.Context, req SendAirtimeInternalRequest) (DepositResponse, error)
+}
+
+/* BalanceReader
+Reads the account balances, see GetAcctBalanceContext
+*/
+type BalanceReader interface {
+	GetAcctBalanceContext(ctx context.Context) (BalanceResponse, error)
+}
+
+/* StatementReader
+Reads the account ministatement, see GetMinistatementContext
+*/
+type StatementReader interface {
+	GetMinistatementContext(ctx context.Context, req MinistatementRequest) (MinistatementResponse, error)
+}
+
+/* AccountVerifier
+Checks mobile money accounts, see VerifyAccountValidityContext
+*/
+type AccountVerifier interface {
+	VerifyAccountValidityContext(ctx context.Context, msisdn string) (bool, error)
+}
+
+/* NotificationReceiver
+Verifies the notifications posted by the gateway
+*/
+type NotificationReceiver interface {
+	ReceivePaymentNotification(date_time, amount, narrative, network_ref, external_ref, msisdn, signature string) (PaymentNotificationResponse, error)
+	ReceivePaymentFailureNotification(failed_transaction_reference, transaction_init_date, verification string) (PaymentFailureNotificationResponse, error)
+}
+
+/* Client
+All gateway methods, implemented by *YoAPI.
+Depend on Client or on the smaller interfaces to substitute mocks (see yopaytest.MockClient) or wrap the calls.
+*/
+type Client interface {
+	Depositor
+	Withdrawer
+	TransactionChecker
+	Transferrer
+	AirtimeSender
+	BalanceReader
+	StatementReader
+	AccountVerifier
+	NotificationReceiver
+}
+
+var _ Client = (*YoAPI)(nil)
@@ -0,0 +1,147 @@
+// yopay project keys.go
+package yopay
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+/* VerificationKey
+Public key verifying notification signatures
+*/
+type VerificationKey struct {
+	PublicKey *rsa.PublicKey
+	// the certificate the key comes from, nil for bare public keys
+	Certificate *x509.Certificate
+}
+
+/* KeyProvider
+Source of notification verification keys, implement it to fetch keys from your own storage.
+It is called for every notification so it should return keys parsed beforehand
+*/
+type KeyProvider interface {
+	VerificationKeys() ([]VerificationKey, error)
+}
+
+/* KeySet
+Fixed list of parsed keys
+*/
+type KeySet []VerificationKey
+
+func (s KeySet) VerificationKeys() ([]VerificationKey, error) {
+	return s, nil
+}
+
+/* ParseKeysPEM
+Parse every "CERTIFICATE", "PUBLIC KEY" and "RSA PUBLIC KEY" block of data
+*/
+func ParseKeysPEM(data []byte) (KeySet, error) {
+	var keys KeySet
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		var key VerificationKey
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			key.Certificate = cert
+			key.PublicKey, _ = cert.PublicKey.(*rsa.PublicKey)
+		case "PUBLIC KEY":
+			pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			key.PublicKey, _ = pub.(*rsa.PublicKey)
+		case "RSA PUBLIC KEY":
+			pub, err := x509.ParsePKCS1PublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			key.PublicKey = pub
+		default:
+			continue
+		}
+		if key.PublicKey == nil {
+			return nil, fmt.Errorf("%s is not an RSA key", block.Type)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no keys found in PEM data")
+	}
+	return keys, nil
+}
+
+/* LoadKeysFile
+Read PEM encoded certificates and public keys from file, see ParseKeysPEM
+*/
+func LoadKeysFile(path string) (KeySet, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeysPEM(data)
+}
+
+var (
+	yoKeysOnce sync.Once
+	yoKeys     KeySet
+	yoKeysErr  error
+)
+
+// the built-in Yo! certificate, parsed once
+func defaultKeys() (KeySet, error) {
+	yoKeysOnce.Do(func() {
+		yoKeys, yoKeysErr = ParseKeysPEM([]byte(pub_crt))
+	})
+	return yoKeys, yoKeysErr
+}
+
+func (api *YoAPI) verificationKeys() ([]VerificationKey, error) {
+	if api.NotificationKeys != nil {
+		return api.NotificationKeys.VerificationKeys()
+	}
+	return defaultKeys()
+}
+
+// verifySignature checks the base64 signature of data, SHA1 with RSA PKCS#1 v1.5, against all trusted keys
+func (api *YoAPI) verifySignature(data string, signature string) (bool, error) {
+	sign, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, err
+	}
+	keys, err := api.verificationKeys()
+	if err != nil {
+		return false, err
+	}
+
+	digest := sha1.Sum([]byte(data))
+	now := time.Now()
+	err = errors.New("no valid notification verification key")
+	for _, key := range keys {
+		if api.CheckKeyValidity && key.Certificate != nil &&
+			(now.Before(key.Certificate.NotBefore) || now.After(key.Certificate.NotAfter)) {
+			continue
+		}
+		err = rsa.VerifyPKCS1v15(key.PublicKey, crypto.SHA1, digest[:], sign)
+		if err == nil {
+			return true, nil
+		}
+	}
+	return false, err
+}
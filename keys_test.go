@@ -0,0 +1,70 @@
+package yopay
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T, notAfter time.Time) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "yopay test"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func testSign(t *testing.T, key *rsa.PrivateKey, data string) string {
+	digest := sha1.Sum([]byte(data))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestNotificationKeyRotation(t *testing.T) {
+	oldKey, oldCert := testCertificate(t, time.Now().Add(-time.Hour))
+	newKey, newCert := testCertificate(t, time.Now().Add(time.Hour))
+	keys, err := ParseKeysPEM(append(oldCert, newCert...))
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("parse keys: %d %v", len(keys), err)
+	}
+	yo := newTestingApi(t)
+	yo.NotificationKeys = keys
+
+	for _, key := range []*rsa.PrivateKey{oldKey, newKey} {
+		r, err := yo.ReceivePaymentFailureNotification("1234", "2019-09-04 10:00:00", testSign(t, key, "12342019-09-04 10:00:00"))
+		if err != nil || !r.Verified {
+			t.Fatalf("notification not verified: %v", err)
+		}
+	}
+
+	yo.CheckKeyValidity = true
+	if r, _ := yo.ReceivePaymentFailureNotification("1234", "2019-09-04 10:00:00", testSign(t, oldKey, "12342019-09-04 10:00:00")); r.Verified {
+		t.Fatalf("expired certificate accepted")
+	}
+
+	yo.NotificationKeys = nil
+	if r, _ := yo.ReceivePaymentFailureNotification("1234", "2019-09-04 10:00:00", testSign(t, newKey, "12342019-09-04 10:00:00")); r.Verified {
+		t.Fatalf("untrusted key accepted")
+	}
+}
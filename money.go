@@ -0,0 +1,170 @@
+// yopay project money.go
+package yopay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// minor units per whole unit, the gateway amounts have at most two decimals
+const minorUnits = 100
+
+var ErrInvalidAmount = errors.New("yopay: invalid amount")
+
+/* Money
+Exact amount in hundredths of the currency unit with the Yo! currency code, e.g. "UGX-MTNMM".
+Request amounts are sent without the currency, which is taken from the CurrencyCode
+of the request where it has one. Negative amounts appear in ministatements only,
+requests with negative amounts are refused.
+*/
+type Money struct {
+	Minor    int64
+	Currency CurrencyCode
+}
+
+/* NewMoney
+Money of units whole units, units beyond the range of Money are refused with ErrInvalidAmount
+*/
+func NewMoney(units int64, currency CurrencyCode) (Money, error) {
+	if units > math.MaxInt64/minorUnits || units < math.MinInt64/minorUnits {
+		return Money{}, fmt.Errorf("%w: %d units out of range", ErrInvalidAmount, units)
+	}
+	return Money{Minor: units * minorUnits, Currency: currency}, nil
+}
+
+/* ParseMoney
+Parse a decimal amount such as "1500", "1500.5" or "1500.50", negative and malformed amounts
+and amounts with more than two significant decimals are refused with ErrInvalidAmount
+*/
+func ParseMoney(amount string, currency CurrencyCode) (Money, error) {
+	m, err := parseSignedMoney(amount, currency)
+	if err == nil && m.Minor < 0 {
+		err = fmt.Errorf("%w: negative amount %q", ErrInvalidAmount, amount)
+	}
+	if err != nil {
+		return Money{}, err
+	}
+	return m, nil
+}
+
+// parseSignedMoney is ParseMoney accepting a leading minus for ministatement debits
+func parseSignedMoney(amount string, currency CurrencyCode) (Money, error) {
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if trimmed := strings.TrimRight(frac, "0"); len(trimmed) <= 2 {
+		frac = trimmed + strings.Repeat("0", 2-len(trimmed))
+	} else {
+		return Money{}, fmt.Errorf("%w: too many decimals in %q", ErrInvalidAmount, amount)
+	}
+	if len(whole) == 0 || strings.Trim(whole, "0123456789") != "" || strings.Trim(frac, "0123456789") != "" {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > math.MaxInt64/minorUnits-1 {
+		return Money{}, fmt.Errorf("%w: %q out of range", ErrInvalidAmount, amount)
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+	minor := units*minorUnits + cents
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+/* Decimal
+Amount in the gateway notation, "1500" for whole amounts, "1500.50" otherwise
+*/
+func (m Money) Decimal() string {
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	if minor%minorUnits == 0 {
+		return fmt.Sprintf("%s%d", sign, minor/minorUnits)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/minorUnits, minor%minorUnits)
+}
+
+func (m Money) String() string {
+	if len(m.Currency) == 0 {
+		return m.Decimal()
+	}
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+/* MarshalText
+Encodes the amount of a request, negative amounts are refused
+*/
+func (m Money) MarshalText() ([]byte, error) {
+	if m.Minor < 0 {
+		return nil, fmt.Errorf("%w: negative amount %s", ErrInvalidAmount, m.Decimal())
+	}
+	return []byte(m.Decimal()), nil
+}
+
+/* UnmarshalText
+Decodes the amount keeping the currency already set
+*/
+func (m *Money) UnmarshalText(text []byte) error {
+	parsed, err := parseSignedMoney(string(text), m.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// currencyOf reconciles the CurrencyCode of a request with the currency of its amount
+func currencyOf(currency_code CurrencyCode, amount Money) (CurrencyCode, error) {
+	if len(currency_code) == 0 {
+		return amount.Currency, nil
+	}
+	if len(amount.Currency) > 0 && amount.Currency != currency_code {
+		return "", fmt.Errorf("%w: amount in %s for CurrencyCode %s", ErrInvalidAmount, amount.Currency, currency_code)
+	}
+	return currency_code, nil
+}
+
+/* Money
+Amount of the transaction in CurrencyCode
+*/
+func (s TransactionStatus) Money() (Money, error) {
+	return ParseMoney(s.Amount, s.CurrencyCode)
+}
+
+/* Money
+Balance of the currency
+*/
+func (b CurrencyBalance) Money() (Money, error) {
+	return parseSignedMoney(b.Balance, b.Code)
+}
+
+/* Money
+Amount of the transaction, negative for debits
+*/
+func (t StatementTransaction) Money() (Money, error) {
+	return parseSignedMoney(t.Amount, t.Currency)
+}
+
+/* BalanceMoney
+Account balance after the transaction
+*/
+func (t StatementTransaction) BalanceMoney() (Money, error) {
+	return parseSignedMoney(t.Balance, t.Currency)
+}
@@ -0,0 +1,96 @@
+package yopay
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	for amount, minor := range map[string]int64{
+		"1500":      150000,
+		"1500.5":    150050,
+		"1500.50":   150050,
+		"1500.500":  150050,
+		" 0.01 ":    1,
+		"1500.":     150000,
+		"000120.10": 12010,
+	} {
+		m, err := ParseMoney(amount, "UGX-MTNMM")
+		if err != nil || m.Minor != minor || m.Currency != "UGX-MTNMM" {
+			t.Errorf("ParseMoney(%q) = %+v, %v", amount, m, err)
+		}
+	}
+	for _, amount := range []string{"", "-5", "1.005", "1,000", "1e3", ".5", "+5", "abc", "99999999999999999999"} {
+		if _, err := ParseMoney(amount, ""); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("ParseMoney(%q) accepted: %v", amount, err)
+		}
+	}
+}
+
+func TestNewMoney(t *testing.T) {
+	if m, err := NewMoney(1500, "UGX-MTNMM"); err != nil || m != (Money{Minor: 150000, Currency: "UGX-MTNMM"}) {
+		t.Fatalf("NewMoney(1500) = %+v, %v", m, err)
+	}
+	for _, units := range []int64{1 << 62, math.MaxInt64/100 + 1, math.MinInt64 / 99, math.MinInt64} {
+		if m, err := NewMoney(units, ""); !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("NewMoney(%d) wrapped to %+v", units, m)
+		}
+	}
+	if _, err := NewMoney(math.MaxInt64/100, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	yo := newTestingApi(t)
+	yo.Observer = func(e Exchange) { t.Fatalf("out of range amount sent: %s", e.Request) }
+	if _, err := yo.DepositFunds("256771234567", 1<<62, "overflow"); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("out of range deposit accepted: %v", err)
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	for _, c := range []struct {
+		m    Money
+		want string
+	}{
+		{Money{Minor: 150000}, "1500"},
+		{Money{Minor: 150050}, "1500.50"},
+		{Money{Minor: -5}, "-0.05"},
+		{Money{Minor: 150000, Currency: "UGX-MTNMM"}, "1500 UGX-MTNMM"},
+	} {
+		if got := c.m.String(); got != c.want {
+			t.Errorf("%+v formatted %q, want %q", c.m, got, c.want)
+		}
+	}
+	if _, err := xml.Marshal(WithdrawFundsRequest{Amount: Money{Minor: -100}}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("negative amount marshaled: %v", err)
+	}
+}
+
+func TestResponseMoney(t *testing.T) {
+	var r MinistatementResponse
+	data := `<Response><Transactions><Transaction><Currency>UGX-MTNMM</Currency><Amount>-1500.50</Amount><Balance>2000</Balance></Transaction></Transactions></Response>`
+	if err := xml.Unmarshal([]byte(data), &r); err != nil {
+		t.Fatal(err)
+	}
+	tx := r.Transactions.Transaction[0]
+	if m, err := tx.Money(); err != nil || m != (Money{Minor: -150050, Currency: "UGX-MTNMM"}) {
+		t.Fatalf("amount %+v %v", m, err)
+	}
+	if m, err := tx.BalanceMoney(); err != nil || m.Minor != 200000 {
+		t.Fatalf("balance %+v %v", m, err)
+	}
+	if _, err := (TransactionStatus{Amount: "-1"}).Money(); err == nil {
+		t.Fatal("negative transaction amount accepted")
+	}
+}
+
+func TestInternalTransferCurrency(t *testing.T) {
+	yo := newTestingApi(t)
+	_, err := yo.InternalTransferContext(context.Background(), InternalTransferRequest{CurrencyCode: "UGX-MTNMM", Amount: Money{Minor: 1000, Currency: "UGX-MTNAT"}})
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("currency mismatch accepted: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+// yopay project msisdn.go
+package yopay
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/* Network
+Ugandan mobile network operator
+*/
+type Network string
+
+const (
+	NetworkMTN      Network = "MTN"
+	NetworkAirtel   Network = "Airtel"
+	NetworkUTL      Network = "UTL"
+	NetworkAfricell Network = "Africell"
+	NetworkLyca     Network = "Lycamobile"
+	NetworkSmile    Network = "Smile"
+	NetworkWarid    Network = "Warid"
+	NetworkOrange   Network = "Orange"
+)
+
+const ugandaCountryCode = "256"
+
+// networkPrefixes are the operators by the leading digits of the national number (the digits
+// after 256), the longest matching prefix wins
+var networkPrefixes = map[string]Network{
+	"70":  NetworkAirtel,
+	"74":  NetworkAirtel,
+	"75":  NetworkAirtel,
+	"71":  NetworkUTL,
+	"720": NetworkSmile,
+	"726": NetworkLyca,
+	"727": NetworkLyca,
+	"76":  NetworkMTN,
+	"77":  NetworkMTN,
+	"78":  NetworkMTN,
+	"79":  NetworkAfricell,
+}
+
+/* NetworkPrefixes
+Copy of the operators by the leading digits of the national number used by MSISDN.Network
+*/
+func NetworkPrefixes() map[string]Network {
+	result := make(map[string]Network, len(networkPrefixes))
+	for prefix, network := range networkPrefixes {
+		result[prefix] = network
+	}
+	return result
+}
+
+var (
+	ErrInvalidMSISDN      = errors.New("yopay: invalid msisdn")
+	ErrUnsupportedNetwork = errors.New("yopay: unsupported network")
+)
+
+/* MSISDN
+Ugandan mobile number in the Yo! format 256772123456
+*/
+type MSISDN string
+
+/* ParseMSISDN
+Normalize a number entered as 0772123456, 772123456, +256 772 123 456, 00256-772-123456 etc.
+into the Yo! format and check it belongs to a known mobile network
+*/
+func ParseMSISDN(s string) (MSISDN, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '\t':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+	digits = strings.TrimPrefix(digits, "+")
+	if strings.HasPrefix(digits, "00") {
+		digits = digits[2:]
+	}
+	if len(digits) == 0 || strings.Trim(digits, "0123456789") != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidMSISDN, s)
+	}
+	switch {
+	case len(digits) == 10 && digits[0] == '0':
+		digits = ugandaCountryCode + digits[1:]
+	case len(digits) == 9:
+		digits = ugandaCountryCode + digits
+	}
+	if len(digits) != 12 || !strings.HasPrefix(digits, ugandaCountryCode) {
+		return "", fmt.Errorf("%w: %q is not a Ugandan mobile number", ErrInvalidMSISDN, s)
+	}
+	m := MSISDN(digits)
+	if len(m.Network()) == 0 {
+		return "", fmt.Errorf("%w: %q is not in a mobile number range", ErrInvalidMSISDN, s)
+	}
+	return m, nil
+}
+
+/* Network
+Operator of the number, empty when no prefix of NetworkPrefixes matches
+*/
+func (m MSISDN) Network() Network {
+	national := strings.TrimPrefix(string(m), ugandaCountryCode)
+	for n := len(national); n > 0; n-- {
+		if network, ok := networkPrefixes[national[:n]]; ok {
+			return network
+		}
+	}
+	return ""
+}
+
+/* Currency
+Code of Currencies of kind on the network of the number, false when the network has none
+*/
+func (m MSISDN) Currency(kind CurrencyKind) (CurrencyCode, bool) {
+	network := m.Network()
+	for code, info := range currencies {
+		if info.Network == network && info.Kind == kind {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// normalizeMSISDN returns the Yo! format of account, or account unchanged for Validate to report
+func normalizeMSISDN(account string) string {
+	if m, err := ParseMSISDN(account); err == nil {
+		return string(m)
+	}
+	return account
+}
@@ -0,0 +1,79 @@
+package yopay
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseMSISDN(t *testing.T) {
+	for input, want := range map[string]MSISDN{
+		"256772123456":       "256772123456",
+		"0772123456":         "256772123456",
+		"772123456":          "256772123456",
+		"+256 772 123 456":   "256772123456",
+		"00256-772-123456":   "256772123456",
+		" (0772) 123.456 ":   "256772123456",
+		"+256 (0)772123456 ": "",
+	} {
+		got, err := ParseMSISDN(input)
+		if len(want) == 0 {
+			if !errors.Is(err, ErrInvalidMSISDN) {
+				t.Errorf("ParseMSISDN(%q) accepted: %s", input, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("ParseMSISDN(%q) = %s, %v", input, got, err)
+		}
+	}
+	for _, input := range []string{"", "abc", "25677212345", "254712123456", "256414123456", "0772-12345x"} {
+		if _, err := ParseMSISDN(input); !errors.Is(err, ErrInvalidMSISDN) {
+			t.Errorf("ParseMSISDN(%q) accepted", input)
+		}
+	}
+}
+
+func TestMSISDNNetwork(t *testing.T) {
+	for number, network := range map[MSISDN]Network{
+		"256772123456": NetworkMTN,
+		"256392123456": "",
+		"256752123456": NetworkAirtel,
+		"256712123456": NetworkUTL,
+		"256720123456": NetworkSmile,
+		"256726123456": NetworkLyca,
+	} {
+		if got := number.Network(); got != network {
+			t.Errorf("%s on %q, want %q", number, got, network)
+		}
+	}
+	if code, ok := MSISDN("256702123456").Currency(MobileMoney); !ok || code != UGXAirtelMobileMoney {
+		t.Fatalf("Airtel mobile money %s", code)
+	}
+	if code, ok := MSISDN("256772123456").Currency(Airtime); !ok || code != UGXMTNAirtime {
+		t.Fatalf("MTN airtime %s", code)
+	}
+
+	prefixes := NetworkPrefixes()
+	if prefixes["77"] != NetworkMTN {
+		t.Fatalf("prefixes %v", prefixes)
+	}
+	prefixes["77"] = NetworkAirtel
+	if MSISDN("256772123456").Network() != NetworkMTN {
+		t.Fatal("prefixes modified through NetworkPrefixes")
+	}
+}
+
+func TestMobileMoneyAccount(t *testing.T) {
+	yo := newTestingApi(t)
+	ctx := context.Background()
+	if _, err := yo.WithdrawFundsContext(ctx, WithdrawFundsRequest{Account: "0712123456", Amount: Money{Minor: 1000}}); !errors.Is(err, ErrUnsupportedNetwork) {
+		t.Fatalf("withdrawal to UTL accepted: %v", err)
+	}
+	if _, err := yo.DepositFundsContext(ctx, DepositFundsRequest{Account: "0702123456", Amount: Money{Minor: 1000, Currency: UGXMTNMobileMoney}}); !errors.Is(err, ErrUnsupportedNetwork) {
+		t.Fatalf("Airtel number with MTN currency accepted: %v", err)
+	}
+	if account := normalizeMSISDN("+256 772 123456"); account != "256772123456" {
+		t.Fatalf("account %s", account)
+	}
+}
@@ -0,0 +1,154 @@
+// yopay project notify.go
+package yopay
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// dates of the notifications and the ministatement, in East Africa Time
+const gatewayTimeLayout = "2006-01-02 15:04:05"
+
+var eastAfricaTime = time.FixedZone("EAT", 3*60*60)
+
+/* NotificationHandler
+http.Handler for the notifications the gateway posts to InstantNotificationUrl and FailureNotificationUrl.
+Failure notifications are recognized by the failed_transaction_reference field, so both urls may
+point to the same path or the handler may be registered on two paths of one mux.
+The signature of every notification is verified, unverified requests are rejected with 403
+and never reach the callbacks.
+*/
+type NotificationHandler struct {
+	/* API
+	   The client verifying the notification signatures
+	   Required.
+	*/
+	API *YoAPI
+
+	/* OnPayment
+	   Called with every verified payment notification.
+	   Returning an error responds with 500 so the gateway delivers the notification again later.
+	*/
+	OnPayment func(ctx context.Context, notification PaymentNotificationResponse) error
+
+	/* OnFailure
+	   Called with every verified failure notification.
+	   Returning an error responds with 500 so the gateway delivers the notification again later.
+	*/
+	OnFailure func(ctx context.Context, notification PaymentFailureNotificationResponse) error
+
+	/* Store
+	   Deduplicates notifications, payments by network_ref and external_ref, failures by
+	   failed_transaction_reference
+	   Optional.
+	   Repeated notifications reach the callbacks with Duplicate set and are acknowledged to the gateway.
+	   Default: nil, no deduplication
+	*/
+	Store NotificationStore
+
+	/* MaxAge
+	   Payment notifications whose date_time is further than MaxAge from now are rejected with 403
+	   Optional.
+	   Default: 0, no check
+	*/
+	MaxAge time.Duration
+}
+
+func (h *NotificationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if _, ok := r.PostForm["failed_transaction_reference"]; ok {
+		h.serveFailure(w, r)
+		return
+	}
+	h.servePayment(w, r)
+}
+
+func (h *NotificationHandler) servePayment(w http.ResponseWriter, r *http.Request) {
+	notification, err := h.API.ReceivePaymentNotification(
+		r.PostForm.Get("date_time"),
+		r.PostForm.Get("amount"),
+		r.PostForm.Get("narrative"),
+		r.PostForm.Get("network_ref"),
+		r.PostForm.Get("external_ref"),
+		r.PostForm.Get("msisdn"),
+		r.PostForm.Get("signature"),
+	)
+	if err != nil || !notification.Verified {
+		http.Error(w, "notification not verified", http.StatusForbidden)
+		return
+	}
+	if h.MaxAge > 0 {
+		at, err := time.ParseInLocation(gatewayTimeLayout, notification.DateTime, eastAfricaTime)
+		if err != nil {
+			http.Error(w, "bad date_time", http.StatusBadRequest)
+			return
+		}
+		if age := time.Since(at); age > h.MaxAge || age < -h.MaxAge {
+			http.Error(w, "notification expired", http.StatusForbidden)
+			return
+		}
+	}
+	key := "payment:" + notification.NetworkRef + "/" + notification.ExternalRef
+	notification.Duplicate, err = h.remember(key)
+	if err != nil {
+		http.Error(w, "notification not processed", http.StatusInternalServerError)
+		return
+	}
+	if h.OnPayment != nil {
+		if err := h.OnPayment(r.Context(), notification); err != nil {
+			h.forget(key, notification.Duplicate)
+			http.Error(w, "notification not processed", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Write([]byte("OK"))
+}
+
+func (h *NotificationHandler) serveFailure(w http.ResponseWriter, r *http.Request) {
+	notification, err := h.API.ReceivePaymentFailureNotification(
+		r.PostForm.Get("failed_transaction_reference"),
+		r.PostForm.Get("transaction_init_date"),
+		r.PostForm.Get("verification"),
+	)
+	if err != nil || !notification.Verified {
+		http.Error(w, "notification not verified", http.StatusForbidden)
+		return
+	}
+	key := "failure:" + notification.FailedTransactionReference
+	notification.Duplicate, err = h.remember(key)
+	if err != nil {
+		http.Error(w, "notification not processed", http.StatusInternalServerError)
+		return
+	}
+	if h.OnFailure != nil {
+		if err := h.OnFailure(r.Context(), notification); err != nil {
+			h.forget(key, notification.Duplicate)
+			http.Error(w, "notification not processed", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Write([]byte("OK"))
+}
+
+func (h *NotificationHandler) remember(key string) (bool, error) {
+	if h.Store == nil {
+		return false, nil
+	}
+	return h.Store.Remember(key)
+}
+
+// forget lets the gateway retry a notification the callback failed to process
+func (h *NotificationHandler) forget(key string, duplicate bool) {
+	if h.Store != nil && !duplicate {
+		h.Store.Forget(key)
+	}
+}
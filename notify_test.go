@@ -0,0 +1,108 @@
+package yopay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationHandlerRejectsUnverified(t *testing.T) {
+	called := false
+	h := &NotificationHandler{
+		API: newTestingApi(t),
+		OnPayment: func(ctx context.Context, n PaymentNotificationResponse) error {
+			called = true
+			return nil
+		},
+		OnFailure: func(ctx context.Context, n PaymentFailureNotificationResponse) error {
+			called = true
+			return nil
+		},
+	}
+
+	form := url.Values{
+		"date_time":    {"2019-09-04 10:00:00"},
+		"amount":       {"1000"},
+		"narrative":    {"test"},
+		"network_ref":  {"1234"},
+		"external_ref": {"inv-1"},
+		"msisdn":       {"256771234567"},
+		"signature":    {"c2lnbmF0dXJl"},
+	}
+	req := httptest.NewRequest("POST", "/yo/notify", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || called {
+		t.Fatalf("forged notification accepted: %d, callback called %v", w.Code, called)
+	}
+
+	form = url.Values{
+		"failed_transaction_reference": {"1234"},
+		"transaction_init_date":        {"2019-09-04 10:00:00"},
+		"verification":                 {"c2lnbmF0dXJl"},
+	}
+	req = httptest.NewRequest("POST", "/yo/failure", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || called {
+		t.Fatalf("forged failure notification accepted: %d, callback called %v", w.Code, called)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/yo/notify", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET accepted: %d", w.Code)
+	}
+}
+
+func TestNotificationHandlerDeduplicates(t *testing.T) {
+	key, cert := testCertificate(t, time.Now().Add(time.Hour))
+	yo := newTestingApi(t)
+	yo.NotificationKeys, _ = ParseKeysPEM(cert)
+	var received []PaymentNotificationResponse
+	h := &NotificationHandler{
+		API: yo,
+		OnPayment: func(ctx context.Context, n PaymentNotificationResponse) error {
+			received = append(received, n)
+			return nil
+		},
+		Store:  NewMemoryStore(0),
+		MaxAge: time.Hour,
+	}
+	post := func(dateTime string) int {
+		form := url.Values{
+			"date_time":    {dateTime},
+			"amount":       {"1000"},
+			"narrative":    {"test"},
+			"network_ref":  {"1234"},
+			"external_ref": {"inv-1"},
+			"msisdn":       {"256771234567"},
+			"signature":    {testSign(t, key, dateTime+"1000test1234inv-1256771234567")},
+		}
+		req := httptest.NewRequest("POST", "/yo/notify", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	now := time.Now().In(eastAfricaTime).Format(gatewayTimeLayout)
+	if code := post(now); code != http.StatusOK {
+		t.Fatalf("notification rejected: %d", code)
+	}
+	if code := post(now); code != http.StatusOK {
+		t.Fatalf("duplicate not acknowledged: %d", code)
+	}
+	if len(received) != 2 || received[0].Duplicate || !received[1].Duplicate {
+		t.Fatalf("duplicate not reported: %+v", received)
+	}
+	if code := post("2019-09-04 10:00:00"); code != http.StatusForbidden || len(received) != 2 {
+		t.Fatalf("stale notification accepted: %d", code)
+	}
+}
@@ -0,0 +1,206 @@
+// yopay project requests.go
+package yopay
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+/* Bool
+Boolean in the gateway notation "TRUE" / "FALSE"
+*/
+type Bool bool
+
+func (b Bool) MarshalText() ([]byte, error) {
+	if b {
+		return []byte("TRUE"), nil
+	}
+	return []byte("FALSE"), nil
+}
+
+func (b *Bool) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "TRUE", "true", "1":
+		*b = true
+	case "FALSE", "false", "0", "":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %q", text)
+	}
+	return nil
+}
+
+// credentials and method name, the first elements of every request
+type requestHeader struct {
+	APIUsername string
+	APIPassword string
+	Method      string
+}
+
+func (h *requestHeader) setHeader(header requestHeader) {
+	*h = header
+}
+
+// request is implemented by all typed request structs
+type request interface {
+	method() string
+	setHeader(header requestHeader)
+	Validate() error
+}
+
+// envelope of every request, Request holds a pointer to the typed request struct
+type autoCreateRequest struct {
+	XMLName xml.Name `xml:"AutoCreate"`
+	Request request
+}
+
+/* DepositFundsRequest
+acdepositfunds request, see DepositFunds
+*/
+type DepositFundsRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+	Account                       string
+	Amount                        Money
+	Narrative                     string
+	ExternalReference             string `xml:",omitempty"`
+	InternalReference             string `xml:",omitempty"`
+	ProviderReferenceText         string `xml:",omitempty"`
+	NonBlocking                   Bool   `xml:",omitempty"`
+	InstantNotificationUrl        string `xml:",omitempty"`
+	FailureNotificationUrl        string `xml:",omitempty"`
+	AuthenticationSignatureBase64 string `xml:",omitempty"`
+}
+
+func (r *DepositFundsRequest) method() string { return "acdepositfunds" }
+
+/* TransactionCheckStatusRequest
+actransactioncheckstatus request, see CheckTransactionStatus
+*/
+type TransactionCheckStatusRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+	DepositTransactionType      string
+	TransactionReference        string `xml:",omitempty"`
+	PrivateTransactionReference string `xml:",omitempty"`
+}
+
+func (r *TransactionCheckStatusRequest) method() string { return "actransactioncheckstatus" }
+
+/* InternalTransferRequest
+acinternaltransfer request, see InternalTransfer
+*/
+type InternalTransferRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+	CurrencyCode       CurrencyCode
+	BeneficiaryAccount string
+	BeneficiaryEmail   string
+	Narrative          string
+	Amount             Money
+	InternalReference  string `xml:",omitempty"`
+	ExternalReference  string `xml:",omitempty"`
+}
+
+func (r *InternalTransferRequest) method() string { return "acinternaltransfer" }
+
+// acacctbalance takes no parameters
+type acctBalanceRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+}
+
+func (r *acctBalanceRequest) method() string { return "acacctbalance" }
+
+/* MinistatementRequest
+acgetministatement request, see GetMinistatement
+*/
+type MinistatementRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+	TransactionEntryDesignation string
+	StartDate                   string       `xml:",omitempty"`
+	EndDate                     string       `xml:",omitempty"`
+	TransactionStatus           string       `xml:",omitempty"`
+	CurrencyCode                CurrencyCode `xml:",omitempty"`
+	ResultSetLimit              string       `xml:",omitempty"`
+	ExternalReference           string       `xml:",omitempty"`
+}
+
+func (r *MinistatementRequest) method() string { return "acgetministatement" }
+
+/* SendAirtimeMobileRequest
+acsendairtimemobile request, see SendAirtimeMobile
+*/
+type SendAirtimeMobileRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+	Account               string
+	Amount                Money
+	Narrative             string
+	NonBlocking           Bool   `xml:",omitempty"`
+	ExternalReference     string `xml:",omitempty"`
+	InternalReference     string `xml:",omitempty"`
+	ProviderReferenceText string `xml:",omitempty"`
+}
+
+func (r *SendAirtimeMobileRequest) method() string { return "acsendairtimemobile" }
+
+/* SendAirtimeInternalRequest
+acsendairtimeinternal request, see SendAirtimeInternal
+*/
+type SendAirtimeInternalRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+	Amount             Money
+	Narrative          string
+	CurrencyCode       CurrencyCode
+	BeneficiaryAccount string
+	BeneficiaryEmail   string
+	InternalReference  string `xml:",omitempty"`
+	ExternalReference  string `xml:",omitempty"`
+}
+
+func (r *SendAirtimeInternalRequest) method() string { return "acsendairtimeinternal" }
+
+/* VerifyAccountValidityRequest
+acverifyaccountvalidity request, see VerifyAccountValidity
+*/
+type VerifyAccountValidityRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+	Account string
+}
+
+func (r *VerifyAccountValidityRequest) method() string { return "acverifyaccountvalidity" }
+
+/* WithdrawFundsRequest
+acwithdrawfunds request, see WithdrawFunds
+*/
+type WithdrawFundsRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	requestHeader
+	NonBlocking           Bool `xml:",omitempty"`
+	Account               string
+	Amount                Money
+	Narrative             string
+	ExternalReference     string `xml:",omitempty"`
+	InternalReference     string `xml:",omitempty"`
+	ProviderReferenceText string `xml:",omitempty"`
+}
+
+func (r *WithdrawFundsRequest) method() string { return "acwithdrawfunds" }
+
+// marshalRequest fills the request header and encodes req inside the AutoCreate envelope
+func (api *YoAPI) marshalRequest(req request) ([]byte, error) {
+	req.setHeader(requestHeader{
+		APIUsername: api.Username,
+		APIPassword: api.Password,
+		Method:      req.method(),
+	})
+	body, err := xml.Marshal(autoCreateRequest{Request: req})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,111 @@
+package yopay
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMarshalRequestEscapes(t *testing.T) {
+	yo := NewYoApi("user", "p<ss")
+	body, err := yo.marshalRequest(&DepositFundsRequest{
+		Account:     "256771234567",
+		Amount:      Money{Minor: 150000},
+		Narrative:   "fish & chips <b>",
+		NonBlocking: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := xml.Header + `<AutoCreate><Request><APIUsername>user</APIUsername><APIPassword>p&lt;ss</APIPassword>` +
+		`<Method>acdepositfunds</Method><Account>256771234567</Account><Amount>1500</Amount>` +
+		`<Narrative>fish &amp; chips &lt;b&gt;</Narrative><NonBlocking>TRUE</NonBlocking></Request></AutoCreate>`
+	if string(body) != expected {
+		t.Fatalf("unexpected request\n got: %s\nwant: %s", body, expected)
+	}
+
+	var parsed struct {
+		Request struct {
+			Method    string
+			Narrative string
+		}
+	}
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Request.Method != "acdepositfunds" || parsed.Request.Narrative != "fish & chips <b>" {
+		t.Fatalf("request does not round trip: %+v", parsed)
+	}
+}
+
+func TestMarshalRequestEscapesUrlOnce(t *testing.T) {
+	yo := NewYoApi("user", "pass")
+	notify := "https://example.com/ipn?key1=a+b&key2=value"
+	body, err := yo.marshalRequest(&DepositFundsRequest{
+		Account:                "256771234567",
+		Amount:                 Money{Minor: 150000},
+		Narrative:              "ok",
+		InstantNotificationUrl: notify,
+		FailureNotificationUrl: notify,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	escaped := "https://example.com/ipn?key1=a+b&amp;key2=value"
+	if strings.Count(string(body), escaped) != 2 || strings.Contains(string(body), "&amp;amp;") {
+		t.Fatalf("url not escaped exactly once: %s", body)
+	}
+
+	var parsed struct {
+		Request DepositFundsRequest
+	}
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Request.InstantNotificationUrl != notify || parsed.Request.FailureNotificationUrl != notify {
+		t.Fatalf("url does not round trip: %+v", parsed.Request)
+	}
+}
+
+func TestDepositFundsContextPerCallReferences(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req struct {
+			Request DepositFundsRequest
+		}
+		xml.Unmarshal(body, &req)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>OK</Status><StatusCode>1</StatusCode>`+
+			`<TransactionReference>%s</TransactionReference></Response></AutoCreate>`, req.Request.ExternalReference)
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(ref string) {
+			defer wg.Done()
+			r, err := yo.DepositFundsContext(context.Background(), DepositFundsRequest{
+				Account:           "256771234567",
+				Amount:            Money{Minor: 100000},
+				Narrative:         "invoice " + ref,
+				ExternalReference: ref,
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if r.TransactionReference != ref {
+				t.Errorf("reference %s was sent as %s", ref, r.TransactionReference)
+			}
+		}(fmt.Sprintf("inv-%d", i))
+	}
+	wg.Wait()
+}
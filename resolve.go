@@ -0,0 +1,68 @@
+// yopay project resolve.go
+package yopay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+/* ErrOutcomeUnknown
+The deposit or withdrawal was interrupted and its status could not be found out,
+money may or may not have moved
+*/
+var ErrOutcomeUnknown = errors.New("yopay: transaction outcome unknown")
+
+func newExternalReference() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "yopay-" + hex.EncodeToString(b)
+}
+
+// resolveOutcome looks up the transaction with external_reference after the request failed with err
+func (api *YoAPI) resolveOutcome(ctx context.Context, external_reference string, err error) (DepositResponse, error) {
+	// YoErrors are answers of the gateway, invalid requests and requests without endpoints were never sent
+	var yoerr *YoError
+	if !api.ResolveTimeouts || errors.As(err, &yoerr) || errors.Is(err, ErrInvalidRequest) || errors.Is(err, ErrNoEndpoints) {
+		return DepositResponse{}, err
+	}
+	if len(external_reference) == 0 {
+		return DepositResponse{}, fmt.Errorf("%w: no external reference: %w", ErrOutcomeUnknown, err)
+	}
+
+	// the caller's ctx is likely expired already, the lookup gets its own deadline
+	timeout := 180 * time.Second
+	if api.QueryTimeout > 0 {
+		timeout = time.Duration(api.QueryTimeout) * time.Second
+	}
+	rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	status, serr := api.CheckTransactionStatusContext(rctx, TransactionCheckStatusRequest{
+		PrivateTransactionReference: external_reference,
+	})
+	if serr != nil {
+		return DepositResponse{}, fmt.Errorf("%w: external reference %s: %w (status check: %v)", ErrOutcomeUnknown, external_reference, err, serr)
+	}
+	switch status.TransactionStatus {
+	case StateSucceeded:
+		return status.DepositResponse, nil
+	case StateFailed:
+		// reported like a request the gateway answered with a failure
+		yoerr := &YoError{
+			Status:           "ERROR",
+			StatusCode:       status.StatusCode,
+			StatusMessage:    status.StatusMessage,
+			ErrorMessageCode: status.ErrorMessageCode,
+			ErrorMessage:     status.ErrorMessage,
+		}
+		if len(yoerr.ErrorMessage) == 0 && len(yoerr.StatusMessage) == 0 {
+			yoerr.ErrorMessage = "transaction " + string(StateFailed)
+		}
+		return status.DepositResponse, yoerr
+	}
+	// PENDING and INDETERMINATE may still go either way
+	return status.DepositResponse, fmt.Errorf("%w: external reference %s: %w (status %q)", ErrOutcomeUnknown, external_reference, err, status.TransactionStatus)
+}
@@ -0,0 +1,96 @@
+package yopay
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveTimeouts(t *testing.T) {
+	var withdrawRef string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req struct {
+			Request struct {
+				Method                      string
+				ExternalReference           string
+				PrivateTransactionReference string
+			}
+		}
+		xml.Unmarshal(body, &req)
+		switch req.Request.Method {
+		case "acwithdrawfunds":
+			withdrawRef = req.Request.ExternalReference
+			// connection dropped in the middle of the response
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Resp`))
+		case "actransactioncheckstatus":
+			if req.Request.PrivateTransactionReference != withdrawRef {
+				fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>ERROR</Status><StatusCode>-1</StatusCode></Response></AutoCreate>`)
+				return
+			}
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>OK</Status><StatusCode>0</StatusCode>`+
+				`<TransactionStatus>SUCCEEDED</TransactionStatus><TransactionReference>ref-1</TransactionReference></Response></AutoCreate>`)
+		}
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+
+	if _, err := yo.WithdrawFunds("256771234567", 100, "test"); err == nil || errors.Is(err, ErrOutcomeUnknown) {
+		t.Fatalf("broken response must be returned as is without ResolveTimeouts, got %v", err)
+	}
+
+	yo.AutoExternalReference = true
+	yo.ResolveTimeouts = true
+	r, err := yo.WithdrawFundsContext(context.Background(), WithdrawFundsRequest{Account: "256771234567", Amount: Money{Minor: 10000}, Narrative: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(withdrawRef, "yopay-") || r.TransactionStatus != StateSucceeded || r.TransactionReference != "ref-1" {
+		t.Fatalf("withdrawal not resolved: %q %+v", withdrawRef, r)
+	}
+
+	yo.AutoExternalReference = false
+	_, err = yo.WithdrawFundsContext(context.Background(), WithdrawFundsRequest{Account: "256771234567", Amount: Money{Minor: 10000}, Narrative: "test"})
+	if !errors.Is(err, ErrOutcomeUnknown) {
+		t.Fatalf("expected unknown outcome, got %v", err)
+	}
+}
+
+func TestResolveTimeoutsStates(t *testing.T) {
+	state := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.Contains(string(body), "<Method>acdepositfunds</Method>") {
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Resp`))
+			return
+		}
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>OK</Status><StatusCode>0</StatusCode>`+
+			`<TransactionStatus>%s</TransactionStatus><TransactionReference>ref-1</TransactionReference></Response></AutoCreate>`, state)
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+	yo.ResolveTimeouts = true
+	req := DepositFundsRequest{Account: "256771234567", Amount: Money{Minor: 10000}, Narrative: "test", ExternalReference: "inv-1"}
+
+	for _, state = range []string{"PENDING", "INDETERMINATE"} {
+		r, err := yo.DepositFundsContext(context.Background(), req)
+		if !errors.Is(err, ErrOutcomeUnknown) || r.TransactionReference != "ref-1" {
+			t.Fatalf("%s: expected unknown outcome with the response, got %+v %v", state, r, err)
+		}
+	}
+
+	state = "FAILED"
+	r, err := yo.DepositFundsContext(context.Background(), req)
+	var yoerr *YoError
+	if !errors.As(err, &yoerr) || errors.Is(err, ErrOutcomeUnknown) || r.TransactionStatus != StateFailed {
+		t.Fatalf("expected gateway error for a failed transaction, got %+v %v", r, err)
+	}
+}
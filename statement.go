@@ -0,0 +1,335 @@
+// yopay project statement.go
+package yopay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* EntryDesignation
+Kind of ministatement entries
+*/
+type EntryDesignation string
+
+const (
+	DesignationTransaction EntryDesignation = "TRANSACTION"
+	DesignationCharges     EntryDesignation = "CHARGES"
+	DesignationAny         EntryDesignation = "ANY"
+)
+
+// NoLimit as StatementQuery.Limit returns all matching transactions
+const NoLimit = -1
+
+/* StatementQuery
+Typed ministatement filter, see QueryMinistatement
+*/
+type StatementQuery struct {
+	/* Start, End
+	   Range of the initiation dates, inclusive, with second precision. The times are converted
+	   to East Africa Time for the gateway.
+	   Default: zero, no bound
+	*/
+	Start time.Time
+	End   time.Time
+
+	/* Statuses
+	   Default: nil, all statuses
+	*/
+	Statuses []TransactionState
+
+	/* Currency
+	   Default: empty, all currencies
+	*/
+	Currency CurrencyCode
+
+	/* Designation
+	   Default: empty, DesignationAny
+	*/
+	Designation EntryDesignation
+
+	/* Limit
+	   Maximum number of transactions returned, NoLimit for all
+	   Default: 0, the gateway default of 15
+	*/
+	Limit int
+
+	ExternalReference string
+}
+
+/* Request
+The acgetministatement request of q, for GetMinistatementContext
+*/
+func (q StatementQuery) Request() MinistatementRequest {
+	req := MinistatementRequest{
+		TransactionEntryDesignation: string(q.Designation),
+		CurrencyCode:                q.Currency,
+		ExternalReference:           q.ExternalReference,
+	}
+	if len(req.TransactionEntryDesignation) == 0 {
+		req.TransactionEntryDesignation = string(DesignationAny)
+	}
+	if !q.Start.IsZero() {
+		req.StartDate = q.Start.In(eastAfricaTime).Format(gatewayTimeLayout)
+	}
+	if !q.End.IsZero() {
+		req.EndDate = q.End.In(eastAfricaTime).Format(gatewayTimeLayout)
+	}
+	statuses := make([]string, len(q.Statuses))
+	for i, s := range q.Statuses {
+		statuses[i] = string(s)
+	}
+	req.TransactionStatus = strings.Join(statuses, ",")
+	switch {
+	case q.Limit == NoLimit:
+		req.ResultSetLimit = "0"
+	case q.Limit != 0:
+		req.ResultSetLimit = strconv.Itoa(q.Limit)
+	}
+	return req
+}
+
+/* QueryMinistatement
+GetMinistatementContext with the filter given by q
+*/
+func (api *YoAPI) QueryMinistatement(ctx context.Context, q StatementQuery) (MinistatementResponse, error) {
+	return api.GetMinistatementContext(ctx, q.Request())
+}
+
+/* Counts
+TotalTransactions and ReturnedTransactions as numbers
+*/
+func (r MinistatementResponse) Counts() (total, returned int, err error) {
+	if total, err = strconv.Atoi(r.TotalTransactions); err != nil {
+		return 0, 0, fmt.Errorf("invalid TotalTransactions %q", r.TotalTransactions)
+	}
+	if returned, err = strconv.Atoi(r.ReturnedTransactions); err != nil {
+		return 0, 0, fmt.Errorf("invalid ReturnedTransactions %q", r.ReturnedTransactions)
+	}
+	return total, returned, nil
+}
+
+// parseGatewayTime parses an East Africa Time date of the gateway, empty dates are zero
+func parseGatewayTime(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(gatewayTimeLayout, s, eastAfricaTime)
+}
+
+/* InitiationTime
+Parsed InitiationDate
+*/
+func (t StatementTransaction) InitiationTime() (time.Time, error) {
+	return parseGatewayTime(t.InitiationDate)
+}
+
+/* CompletionTime
+Parsed CompletionDate, zero while the transaction is not complete
+*/
+func (t StatementTransaction) CompletionTime() (time.Time, error) {
+	return parseGatewayTime(t.CompletionDate)
+}
+
+/* InitiationTime
+Parsed TransactionInitiationDate
+*/
+func (s TransactionStatus) InitiationTime() (time.Time, error) {
+	return parseGatewayTime(s.TransactionInitiationDate)
+}
+
+/* CompletionTime
+Parsed TransactionCompletionDate, zero while the transaction is not complete
+*/
+func (s TransactionStatus) CompletionTime() (time.Time, error) {
+	return parseGatewayTime(s.TransactionCompletionDate)
+}
+
+// transactions requested per window by StatementIterator unless PageSize is set
+const defaultStatementPageSize = 100
+
+/* StatementIterator
+Streams all transactions of a StatementQuery range, oldest first.
+The range is fetched in windows worked out one at a time, a window holding more transactions
+than PageSize is split in halves until the gateway returns all of them, so only one window of
+transactions is held in memory.
+Windows share their boundary second, transactions are deduplicated by TransactionSystemId.
+	it := NewStatementIterator(ctx, api, yopay.StatementQuery{Start: from, End: to})
+	for it.Next() {
+		tx := it.Transaction()
+		...
+	}
+	if err := it.Err(); err != nil {
+		...
+	}
+*/
+type StatementIterator struct {
+	/* PageSize
+	   Transactions requested per window
+	   Default: 100
+	*/
+	PageSize int
+
+	/* Window
+	   Length of the first windows, they are split further when needed
+	   Default: 0, the whole range
+	*/
+	Window time.Duration
+
+	ctx     context.Context
+	reader  StatementReader
+	query   StatementQuery
+	started bool
+	// start of the next window and end of the range, done once the last window was taken
+	cursor, end time.Time
+	step        time.Duration
+	done        bool
+	// halves of split windows still to fetch, the next one last
+	pending []statementWindow
+	buf     []StatementTransaction
+	current StatementTransaction
+	// ids of the window before and the window being streamed
+	previous map[string]bool
+	seen     map[string]bool
+	err      error
+}
+
+type statementWindow struct {
+	start, end time.Time
+}
+
+/* NewStatementIterator
+Iterate the transactions matching q between q.Start and q.End (now when zero), q.Limit is ignored.
+q.Start is required
+*/
+func NewStatementIterator(ctx context.Context, reader StatementReader, q StatementQuery) *StatementIterator {
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	return &StatementIterator{ctx: ctx, reader: reader, query: q}
+}
+
+/* Next
+Advance to the next transaction, false at the end or on error
+*/
+func (it *StatementIterator) Next() bool {
+	if !it.started {
+		it.start()
+	}
+	for len(it.buf) == 0 {
+		if it.err != nil {
+			return false
+		}
+		w, ok := it.nextWindow()
+		if !ok {
+			return false
+		}
+		it.err = it.fetch(w)
+	}
+	it.current = it.buf[0]
+	it.buf = it.buf[1:]
+	return true
+}
+
+/* Transaction
+The transaction Next advanced to
+*/
+func (it *StatementIterator) Transaction() StatementTransaction {
+	return it.current
+}
+
+/* Err
+The error which stopped the iteration, nil when all transactions were returned
+*/
+func (it *StatementIterator) Err() error {
+	return it.err
+}
+
+func (it *StatementIterator) start() {
+	it.started = true
+	start := it.query.Start.Truncate(time.Second)
+	end := it.query.End.Truncate(time.Second)
+	switch {
+	case it.query.Start.IsZero():
+		it.err = errors.New("yopay: statement iterator needs StatementQuery.Start")
+		return
+	case end.Before(start):
+		it.err = fmt.Errorf("%w: End before Start", ErrInvalidRequest)
+		return
+	}
+	step := it.Window
+	if step <= 0 || step > end.Sub(start) {
+		step = end.Sub(start)
+	}
+	if step < time.Second {
+		step = time.Second
+	}
+	it.cursor, it.end, it.step = start, end, step
+}
+
+// nextWindow takes the next split half, or the window at the cursor when there is none
+func (it *StatementIterator) nextWindow() (statementWindow, bool) {
+	if n := len(it.pending); n > 0 {
+		w := it.pending[n-1]
+		it.pending = it.pending[:n-1]
+		return w, true
+	}
+	if it.done {
+		return statementWindow{}, false
+	}
+	w := statementWindow{it.cursor, it.cursor.Add(it.step)}
+	if !w.end.Before(it.end) {
+		w.end = it.end
+		it.done = true
+	}
+	it.cursor = w.end
+	return w, true
+}
+
+// fetch loads window w into buf, or splits it when the gateway returns only part of it
+func (it *StatementIterator) fetch(w statementWindow) error {
+	pageSize := it.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultStatementPageSize
+	}
+	q := it.query
+	q.Start, q.End, q.Limit = w.start, w.end, pageSize
+	r, err := it.reader.GetMinistatementContext(it.ctx, q.Request())
+	if err != nil {
+		return err
+	}
+	total, returned, err := r.Counts()
+	if err != nil {
+		return err
+	}
+	if total > returned {
+		if w.end.Sub(w.start) >= 2*time.Second {
+			mid := w.start.Add(w.end.Sub(w.start) / 2).Truncate(time.Second)
+			it.pending = append(it.pending, statementWindow{mid, w.end}, statementWindow{w.start, mid})
+			return nil
+		}
+		// one second cannot be split, take it whole
+		q.Limit = NoLimit
+		if r, err = it.reader.GetMinistatementContext(it.ctx, q.Request()); err != nil {
+			return err
+		}
+	}
+
+	it.previous, it.seen = it.seen, make(map[string]bool, len(r.Transactions.Transaction))
+	for _, tx := range r.Transactions.Transaction {
+		if id := tx.TransactionSystemId; len(id) > 0 {
+			if it.previous[id] || it.seen[id] {
+				continue
+			}
+			it.seen[id] = true
+		}
+		it.buf = append(it.buf, tx)
+	}
+	// the gateway date format sorts chronologically
+	sort.SliceStable(it.buf, func(i, j int) bool { return it.buf[i].InitiationDate < it.buf[j].InitiationDate })
+	return nil
+}
@@ -0,0 +1,173 @@
+package yopay
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStatementQueryRequest(t *testing.T) {
+	start := time.Date(2024, 3, 1, 21, 30, 0, 0, time.UTC)
+	req := StatementQuery{
+		Start:    start,
+		End:      start.Add(24 * time.Hour),
+		Statuses: []TransactionState{StateSucceeded, StateFailed},
+		Currency: UGXMTNMobileMoney,
+		Limit:    NoLimit,
+	}.Request()
+	if req.StartDate != "2024-03-02 00:30:00" || req.EndDate != "2024-03-03 00:30:00" {
+		t.Fatalf("dates not in EAT: %s %s", req.StartDate, req.EndDate)
+	}
+	if req.TransactionStatus != "SUCCEEDED,FAILED" || req.CurrencyCode != UGXMTNMobileMoney ||
+		req.ResultSetLimit != "0" || req.TransactionEntryDesignation != "ANY" {
+		t.Fatalf("request %+v", req)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	req = StatementQuery{Designation: DesignationCharges, Limit: 20}.Request()
+	if req.StartDate != "" || req.EndDate != "" || req.TransactionStatus != "" || req.ResultSetLimit != "20" || req.TransactionEntryDesignation != "CHARGES" {
+		t.Fatalf("request %+v", req)
+	}
+	if req := (StatementQuery{}).Request(); req.ResultSetLimit != "" {
+		t.Fatalf("default limit sent: %q", req.ResultSetLimit)
+	}
+	req = StatementQuery{Statuses: []TransactionState{"DONE"}, Limit: -5}.Request()
+	if err := req.Validate(); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("invalid query accepted: %+v", req)
+	}
+}
+
+func TestStatementTimes(t *testing.T) {
+	tx := StatementTransaction{InitiationDate: "2024-03-02 00:30:00"}
+	initiated, err := tx.InitiationTime()
+	if err != nil || !initiated.Equal(time.Date(2024, 3, 1, 21, 30, 0, 0, time.UTC)) {
+		t.Fatalf("initiated %v %v", initiated, err)
+	}
+	if completed, err := tx.CompletionTime(); err != nil || !completed.IsZero() {
+		t.Fatalf("pending transaction completed %v %v", completed, err)
+	}
+	if _, err := (TransactionStatus{TransactionCompletionDate: "02/03/2024"}).CompletionTime(); err == nil {
+		t.Fatal("malformed date accepted")
+	}
+
+	total, returned, err := MinistatementResponse{TotalTransactions: "40", ReturnedTransactions: "15"}.Counts()
+	if err != nil || total != 40 || returned != 15 {
+		t.Fatalf("counts %d %d %v", total, returned, err)
+	}
+}
+
+// statementReader serves transactions initiated at times, inclusive of both ends of the range
+type statementReader struct {
+	times    []time.Time
+	requests int
+}
+
+func (r *statementReader) GetMinistatementContext(ctx context.Context, req MinistatementRequest) (MinistatementResponse, error) {
+	r.requests++
+	start, _ := parseGatewayTime(req.StartDate)
+	end, _ := parseGatewayTime(req.EndDate)
+	limit := 15
+	if len(req.ResultSetLimit) > 0 {
+		limit, _ = strconv.Atoi(req.ResultSetLimit)
+	}
+	var response MinistatementResponse
+	total := 0
+	for i, at := range r.times {
+		if at.Before(start) || at.After(end) {
+			continue
+		}
+		total++
+		if limit == 0 || len(response.Transactions.Transaction) < limit {
+			response.Transactions.Transaction = append(response.Transactions.Transaction, StatementTransaction{
+				TransactionSystemId: strconv.Itoa(i),
+				InitiationDate:      at.In(eastAfricaTime).Format(gatewayTimeLayout),
+			})
+		}
+	}
+	response.TotalTransactions = strconv.Itoa(total)
+	response.ReturnedTransactions = strconv.Itoa(len(response.Transactions.Transaction))
+	return response, nil
+}
+
+// collect returns the ids streamed by it, failing on duplicates and out of order transactions
+func collect(t *testing.T, it *StatementIterator) []string {
+	var ids []string
+	seen := map[string]bool{}
+	last := ""
+	for it.Next() {
+		tx := it.Transaction()
+		if seen[tx.TransactionSystemId] {
+			t.Fatalf("transaction %s returned twice", tx.TransactionSystemId)
+		}
+		seen[tx.TransactionSystemId] = true
+		if tx.InitiationDate < last {
+			t.Fatalf("transaction %s out of order", tx.TransactionSystemId)
+		}
+		last = tx.InitiationDate
+		ids = append(ids, tx.TransactionSystemId)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestStatementIteratorSplit(t *testing.T) {
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	reader := &statementReader{}
+	for i := 0; i < 5; i++ {
+		reader.times = append(reader.times, start.Add(time.Duration(i)*time.Second))
+	}
+	// three more in the last second, which cannot be split
+	for i := 0; i < 3; i++ {
+		reader.times = append(reader.times, start.Add(4*time.Second))
+	}
+
+	it := NewStatementIterator(context.Background(), reader, StatementQuery{Start: start, End: start.Add(4 * time.Second)})
+	it.PageSize = 2
+	if ids := collect(t, it); len(ids) != 8 {
+		t.Fatalf("%d transactions returned: %v", len(ids), ids)
+	}
+	// [0,4] [0,2] [0,1] [1,2] [2,4] [2,3] [3,4] and [3,4] again without a limit
+	if reader.requests != 8 {
+		t.Fatalf("%d requests", reader.requests)
+	}
+
+	if it := NewStatementIterator(context.Background(), reader, StatementQuery{}); it.Next() || it.Err() == nil {
+		t.Fatal("iterator without Start did not fail")
+	}
+}
+
+func TestStatementIteratorWindow(t *testing.T) {
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	reader := &statementReader{}
+	// one a minute, 08:05 is on the boundary of both windows
+	for i := 0; i <= 10; i++ {
+		reader.times = append(reader.times, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	it := NewStatementIterator(context.Background(), reader, StatementQuery{Start: start, End: start.Add(10 * time.Minute)})
+	it.PageSize = 10
+	it.Window = 5 * time.Minute
+	if ids := collect(t, it); len(ids) != 11 {
+		t.Fatalf("%d transactions returned: %v", len(ids), ids)
+	}
+	if reader.requests != 2 {
+		t.Fatalf("%d requests", reader.requests)
+	}
+
+	// a second long window over a year is not laid out in advance
+	reader.requests = 0
+	it = NewStatementIterator(context.Background(), reader, StatementQuery{Start: start, End: start.Add(365 * 24 * time.Hour)})
+	it.Window = time.Second
+	if !it.Next() || it.Transaction().TransactionSystemId != "0" {
+		t.Fatalf("first transaction not returned: %v", it.Err())
+	}
+	if reader.requests != 1 || len(it.pending) != 0 {
+		t.Fatalf("%d requests, %d windows pending", reader.requests, len(it.pending))
+	}
+}
@@ -0,0 +1,53 @@
+// yopay project status.go
+package yopay
+
+import "fmt"
+
+/* TransactionState
+Gateway transaction status as reported in DepositResponse, TransactionStatus and ministatement transactions
+*/
+type TransactionState string
+
+const (
+	StateSucceeded     TransactionState = "SUCCEEDED"
+	StatePending       TransactionState = "PENDING"
+	StateFailed        TransactionState = "FAILED"
+	StateIndeterminate TransactionState = "INDETERMINATE"
+)
+
+/* ParseTransactionState
+Parse gateway transaction status, unknown values return an error
+*/
+func ParseTransactionState(s string) (TransactionState, error) {
+	state := TransactionState(s)
+	if !state.IsKnown() {
+		return state, fmt.Errorf("unknown transaction status %q", s)
+	}
+	return state, nil
+}
+
+/* IsKnown
+Report whether s is one of the documented gateway statuses
+*/
+func (s TransactionState) IsKnown() bool {
+	switch s {
+	case StateSucceeded, StatePending, StateFailed, StateIndeterminate:
+		return true
+	}
+	return false
+}
+
+/* IsTerminal
+Report whether the transaction will not change its status any more.
+INDETERMINATE is not terminal, the gateway resolves it later
+*/
+func (s TransactionState) IsTerminal() bool {
+	return s == StateSucceeded || s == StateFailed
+}
+
+/* IsSuccess
+Report whether the transaction completed successfully
+*/
+func (s TransactionState) IsSuccess() bool {
+	return s == StateSucceeded
+}
@@ -0,0 +1,37 @@
+package yopay
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestTransactionState(t *testing.T) {
+	for _, c := range []struct {
+		state             string
+		terminal, success bool
+	}{
+		{"SUCCEEDED", true, true},
+		{"FAILED", true, false},
+		{"PENDING", false, false},
+		{"INDETERMINATE", false, false},
+	} {
+		s, err := ParseTransactionState(c.state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.IsTerminal() != c.terminal || s.IsSuccess() != c.success {
+			t.Errorf("%s: terminal %v success %v", s, s.IsTerminal(), s.IsSuccess())
+		}
+	}
+	if s, err := ParseTransactionState("REVERSED"); err == nil || s.IsKnown() {
+		t.Fatalf("unknown status accepted")
+	}
+
+	var r TransactionStatus
+	if err := xml.Unmarshal([]byte(`<Response><Status>OK</Status><TransactionStatus>SUCCEEDED</TransactionStatus></Response>`), &r); err != nil {
+		t.Fatal(err)
+	}
+	if !r.TransactionStatus.IsSuccess() {
+		t.Fatalf("unexpected status %q", r.TransactionStatus)
+	}
+}
@@ -0,0 +1,173 @@
+// yopay project store.go
+package yopay
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* NotificationStore
+Remembers processed notifications for NotificationHandler deduplication.
+Implementations must be safe for concurrent use
+*/
+type NotificationStore interface {
+	// Remember records key and reports whether it was recorded before
+	Remember(key string) (seen bool, err error)
+	// Forget removes key after its notification could not be processed
+	Forget(key string) error
+}
+
+/* MemoryStore
+NotificationStore keeping the keys in memory
+*/
+type MemoryStore struct {
+	ttl  time.Duration
+	mu   sync.Mutex
+	seen map[string]time.Time
+	// keys in the order they were recorded, oldest first, kept only with a ttl
+	order []memoryEntry
+	now   func() time.Time
+}
+
+type memoryEntry struct {
+	key string
+	at  time.Time
+}
+
+/* NewMemoryStore
+Create in-memory store, keys are dropped ttl after they were recorded, 0 keeps them forever
+*/
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, seen: make(map[string]time.Time), now: time.Now}
+}
+
+func (s *MemoryStore) Remember(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.ttl > 0 {
+		s.expire(now)
+	}
+	if _, ok := s.seen[key]; ok {
+		return true, nil
+	}
+	s.seen[key] = now
+	if s.ttl > 0 {
+		s.order = append(s.order, memoryEntry{key, now})
+	}
+	return false, nil
+}
+
+// expire drops the keys older than ttl from the front of order
+func (s *MemoryStore) expire(now time.Time) {
+	i := 0
+	for ; i < len(s.order) && now.Sub(s.order[i].at) > s.ttl; i++ {
+		e := s.order[i]
+		// a key forgotten and recorded again has a later entry
+		if at, ok := s.seen[e.key]; ok && at.Equal(e.at) {
+			delete(s.seen, e.key)
+		}
+		s.order[i] = memoryEntry{}
+	}
+	s.order = s.order[i:]
+}
+
+func (s *MemoryStore) Forget(key string) error {
+	s.mu.Lock()
+	delete(s.seen, key)
+	s.mu.Unlock()
+	return nil
+}
+
+/* FileStore
+NotificationStore appending the keys to a file so they survive restarts
+*/
+type FileStore struct {
+	mu   sync.Mutex
+	file *os.File
+	seen map[string]bool
+}
+
+/* OpenFileStore
+Open or create the store file at path and load the keys recorded before
+*/
+func OpenFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{file: file, seen: make(map[string]bool)}
+	// no Scanner, a record has no length limit
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "+"):
+				s.seen[decodeStoreKey(line[1:])] = true
+			case strings.HasPrefix(line, "-"):
+				delete(s.seen, decodeStoreKey(line[1:]))
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// decodeStoreKey reads a key written by append, files written before the keys were quoted hold them raw
+func decodeStoreKey(s string) string {
+	if key, err := strconv.Unquote(s); err == nil {
+		return key
+	}
+	return s
+}
+
+func (s *FileStore) Remember(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		return true, nil
+	}
+	// keys come from form fields, quoting keeps one record per line
+	if err := s.append("+" + strconv.Quote(key)); err != nil {
+		return false, err
+	}
+	s.seen[key] = true
+	return false, nil
+}
+
+func (s *FileStore) Forget(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.seen[key] {
+		return nil
+	}
+	if err := s.append("-" + strconv.Quote(key)); err != nil {
+		return err
+	}
+	delete(s.seen, key)
+	return nil
+}
+
+func (s *FileStore) append(line string) error {
+	if _, err := s.file.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
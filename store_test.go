@@ -0,0 +1,114 @@
+package yopay
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications")
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"payment:1/a", "payment:2/b"} {
+		if seen, err := s.Remember(key); seen || err != nil {
+			t.Fatalf("new key %s reported as seen: %v", key, err)
+		}
+	}
+	if seen, _ := s.Remember("payment:1/a"); !seen {
+		t.Fatalf("duplicate not detected")
+	}
+	if err := s.Forget("payment:2/b"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if seen, _ := s.Remember("payment:1/a"); !seen {
+		t.Fatalf("key lost after reopening")
+	}
+	if seen, _ := s.Remember("payment:2/b"); seen {
+		t.Fatalf("forgotten key still seen")
+	}
+}
+
+func TestFileStoreKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications")
+	long := "payment:" + strings.Repeat("x", 100*1024)
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"payment:a\nb/c", "payment:q\"\r/\\", long} {
+		if seen, err := s.Remember(key); seen || err != nil {
+			t.Fatalf("new key %q reported as seen: %v", key, err)
+		}
+	}
+	s.Close()
+
+	s, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, key := range []string{"payment:a\nb/c", "payment:q\"\r/\\", long} {
+		if seen, _ := s.Remember(key); !seen {
+			t.Fatalf("key %.20q lost after reopening", key)
+		}
+	}
+	if seen, _ := s.Remember("payment:a b/c"); seen {
+		t.Fatalf("different key reported as seen")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore(0)
+	if seen, _ := s.Remember("failure:1"); seen {
+		t.Fatalf("new key reported as seen")
+	}
+	if seen, _ := s.Remember("failure:1"); !seen {
+		t.Fatalf("duplicate not detected")
+	}
+	s.Forget("failure:1")
+	if seen, _ := s.Remember("failure:1"); seen {
+		t.Fatalf("forgotten key still seen")
+	}
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	s := NewMemoryStore(time.Minute)
+	clock := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+
+	s.Remember("payment:1")
+	clock = clock.Add(30 * time.Second)
+	s.Remember("payment:2")
+	s.Forget("payment:2")
+	s.Remember("payment:2")
+	clock = clock.Add(40 * time.Second)
+	if seen, _ := s.Remember("payment:3"); seen {
+		t.Fatalf("new key reported as seen")
+	}
+	if len(s.seen) != 2 || len(s.order) != 3 {
+		t.Fatalf("expired keys kept: %v %v", s.seen, s.order)
+	}
+	if seen, _ := s.Remember("payment:2"); !seen {
+		t.Fatalf("key dropped before its ttl")
+	}
+	if seen, _ := s.Remember("payment:1"); seen {
+		t.Fatalf("key kept after its ttl")
+	}
+
+	clock = clock.Add(time.Hour)
+	s.Remember("payment:4")
+	if len(s.seen) != 1 || len(s.order) != 1 {
+		t.Fatalf("expired keys kept: %v %v", s.seen, s.order)
+	}
+}
@@ -0,0 +1,357 @@
+// yopay project validate.go
+package yopay
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxEmailLength is the longest address usable in an SMTP path, RFC 5321 section 4.5.3.1.3.
+// The lengths of the text and reference fields are left to the gateway
+const maxEmailLength = 254
+
+var (
+	ErrInvalidRequest = errors.New("yopay: invalid request")
+
+	errRequired = errors.New("required")
+)
+
+/* FieldError
+Invalid request field, Field is the XML element name
+*/
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+/* ValidationError
+Returned by the Validate methods and by the gateway methods before anything is sent.
+Matches ErrInvalidRequest and the causes of the field errors with errors.Is,
+e.g. ErrInvalidAmount, ErrInvalidCurrency or ErrInvalidMSISDN
+*/
+type ValidationError struct {
+	Method string
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("yopay: invalid %s request: %s", e.Method, strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := []error{ErrInvalidRequest}
+	for _, f := range e.Fields {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// validator collects the field errors of one request
+type validator struct {
+	fields []*FieldError
+}
+
+func (v *validator) add(field string, err error) {
+	if err != nil {
+		v.fields = append(v.fields, &FieldError{Field: field, Err: err})
+	}
+}
+
+func (v *validator) err(method string) error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Method: method, Fields: v.fields}
+}
+
+// text checks the characters of free text
+func (v *validator) text(field, value string, required bool) {
+	if len(value) == 0 {
+		if required {
+			v.add(field, errRequired)
+		}
+		return
+	}
+	v.chars(field, value)
+}
+
+// reference checks the characters of an identifier
+func (v *validator) reference(field, value string) {
+	v.chars(field, value)
+}
+
+// chars refuses what the XML body cannot carry, encoding/xml would replace it silently
+func (v *validator) chars(field, value string) {
+	if !utf8.ValidString(value) {
+		v.add(field, errors.New("invalid UTF-8"))
+		return
+	}
+	if i := strings.IndexFunc(value, func(r rune) bool { return !isXMLChar(r) }); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(value[i:])
+		v.add(field, fmt.Errorf("invalid character %q", r))
+	}
+}
+
+// isXMLChar reports whether r is allowed in an XML 1.0 document, production [2] Char
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xd7ff ||
+		r >= 0xe000 && r <= 0xfffd ||
+		r >= 0x10000 && r <= 0x10ffff
+}
+
+func (v *validator) amount(field string, amount Money) {
+	if amount.Minor <= 0 {
+		v.add(field, fmt.Errorf("%w: %s is not positive", ErrInvalidAmount, amount.Decimal()))
+	}
+}
+
+func (v *validator) msisdn(field, value string) {
+	if len(value) == 0 {
+		v.add(field, errRequired)
+		return
+	}
+	_, err := ParseMSISDN(value)
+	v.add(field, err)
+}
+
+func (v *validator) email(field, value string, required bool) {
+	if len(value) == 0 {
+		if required {
+			v.add(field, errRequired)
+		}
+		return
+	}
+	if len(value) > maxEmailLength {
+		v.add(field, fmt.Errorf("longer than %d characters", maxEmailLength))
+		return
+	}
+	if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value {
+		v.add(field, fmt.Errorf("invalid email address %q", value))
+	}
+}
+
+func (v *validator) url(field, value string) {
+	if len(value) == 0 {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		v.add(field, fmt.Errorf("not an absolute http(s) url %q", value))
+		return
+	}
+	// a url XML escaped by the caller has keys "amp;key", u.Query would drop them for the semicolon
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		if key, err := url.QueryUnescape(key); err == nil && strings.HasPrefix(key, "amp;") {
+			v.add(field, fmt.Errorf("XML escaped url %q, pass the plain url", value))
+			return
+		}
+	}
+}
+
+func (v *validator) currency(field string, code CurrencyCode, kinds ...CurrencyKind) {
+	if len(code) == 0 {
+		v.add(field, errRequired)
+		return
+	}
+	v.add(field, validateCurrency(code, kinds...))
+}
+
+// mobileMoney checks the account of deposits and withdrawals is on a network with mobile money, in currency when it is set
+func (v *validator) mobileMoney(field, account string, currency CurrencyCode) {
+	m, err := ParseMSISDN(account)
+	if err != nil {
+		v.msisdn(field, account)
+		return
+	}
+	code, ok := m.Currency(MobileMoney)
+	if !ok {
+		v.add(field, fmt.Errorf("%w: no mobile money on %s", ErrUnsupportedNetwork, m.Network()))
+	} else if len(currency) > 0 && validateCurrency(currency) == nil && currency != code {
+		v.add(field, fmt.Errorf("%w: %s is on %s, not %s", ErrUnsupportedNetwork, m, m.Network(), currency))
+	}
+}
+
+/* Validate
+Check the request before it is sent, DepositFundsContext calls it
+*/
+func (r *DepositFundsRequest) Validate() error {
+	var v validator
+	if len(r.Amount.Currency) > 0 {
+		v.add("Amount", validateCurrency(r.Amount.Currency, MobileMoney))
+	}
+	v.mobileMoney("Account", r.Account, r.Amount.Currency)
+	v.amount("Amount", r.Amount)
+	v.text("Narrative", r.Narrative, true)
+	v.reference("ExternalReference", r.ExternalReference)
+	v.reference("InternalReference", r.InternalReference)
+	v.text("ProviderReferenceText", r.ProviderReferenceText, false)
+	v.url("InstantNotificationUrl", r.InstantNotificationUrl)
+	v.url("FailureNotificationUrl", r.FailureNotificationUrl)
+	if len(r.AuthenticationSignatureBase64) > 0 {
+		if _, err := base64.StdEncoding.DecodeString(r.AuthenticationSignatureBase64); err != nil {
+			v.add("AuthenticationSignatureBase64", errors.New("invalid base64"))
+		}
+	}
+	return v.err(r.method())
+}
+
+/* Validate
+Check the request before it is sent, WithdrawFundsContext calls it
+*/
+func (r *WithdrawFundsRequest) Validate() error {
+	var v validator
+	if len(r.Amount.Currency) > 0 {
+		v.add("Amount", validateCurrency(r.Amount.Currency, MobileMoney))
+	}
+	v.mobileMoney("Account", r.Account, r.Amount.Currency)
+	v.amount("Amount", r.Amount)
+	v.text("Narrative", r.Narrative, true)
+	v.reference("ExternalReference", r.ExternalReference)
+	v.reference("InternalReference", r.InternalReference)
+	v.text("ProviderReferenceText", r.ProviderReferenceText, false)
+	return v.err(r.method())
+}
+
+/* Validate
+Check the request before it is sent, CheckTransactionStatusContext calls it
+*/
+func (r *TransactionCheckStatusRequest) Validate() error {
+	var v validator
+	if len(r.TransactionReference) == 0 && len(r.PrivateTransactionReference) == 0 {
+		v.add("TransactionReference", errors.New("TransactionReference or PrivateTransactionReference required"))
+	}
+	v.reference("TransactionReference", r.TransactionReference)
+	v.reference("PrivateTransactionReference", r.PrivateTransactionReference)
+	return v.err(r.method())
+}
+
+/* Validate
+Check the request before it is sent, InternalTransferContext calls it
+*/
+func (r *InternalTransferRequest) Validate() error {
+	var v validator
+	v.currency("CurrencyCode", r.CurrencyCode)
+	if len(r.BeneficiaryAccount) == 0 {
+		v.add("BeneficiaryAccount", errRequired)
+	} else if strings.Trim(r.BeneficiaryAccount, "0123456789") != "" {
+		v.add("BeneficiaryAccount", fmt.Errorf("not an account number %q", r.BeneficiaryAccount))
+	}
+	v.email("BeneficiaryEmail", r.BeneficiaryEmail, true)
+	v.text("Narrative", r.Narrative, true)
+	v.amount("Amount", r.Amount)
+	v.reference("InternalReference", r.InternalReference)
+	v.reference("ExternalReference", r.ExternalReference)
+	return v.err(r.method())
+}
+
+func (r *acctBalanceRequest) Validate() error { return nil }
+
+/* Validate
+Check the request before it is sent, GetMinistatementContext calls it
+*/
+func (r *MinistatementRequest) Validate() error {
+	var v validator
+	switch r.TransactionEntryDesignation {
+	case "TRANSACTION", "CHARGES", "ANY":
+	default:
+		v.add("TransactionEntryDesignation", fmt.Errorf("not TRANSACTION, CHARGES or ANY: %q", r.TransactionEntryDesignation))
+	}
+	var start, end time.Time
+	var err error
+	if len(r.StartDate) > 0 {
+		if start, err = time.Parse(gatewayTimeLayout, r.StartDate); err != nil {
+			v.add("StartDate", fmt.Errorf("not in the format YYYY-MM-DD HH:MM:SS: %q", r.StartDate))
+		}
+	}
+	if len(r.EndDate) > 0 {
+		if end, err = time.Parse(gatewayTimeLayout, r.EndDate); err != nil {
+			v.add("EndDate", fmt.Errorf("not in the format YYYY-MM-DD HH:MM:SS: %q", r.EndDate))
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		v.add("EndDate", errors.New("before StartDate"))
+	}
+	if len(r.TransactionStatus) > 0 {
+		for _, s := range strings.Split(r.TransactionStatus, ",") {
+			if _, err := ParseTransactionState(strings.TrimSpace(s)); err != nil {
+				v.add("TransactionStatus", err)
+			}
+		}
+	}
+	if len(r.CurrencyCode) > 0 {
+		v.currency("CurrencyCode", r.CurrencyCode)
+	}
+	if len(r.ResultSetLimit) > 0 {
+		if n, err := strconv.Atoi(r.ResultSetLimit); err != nil || n < 0 {
+			v.add("ResultSetLimit", fmt.Errorf("not a non-negative number %q", r.ResultSetLimit))
+		}
+	}
+	v.reference("ExternalReference", r.ExternalReference)
+	return v.err(r.method())
+}
+
+/* Validate
+Check the request before it is sent, SendAirtimeMobileContext calls it
+*/
+func (r *SendAirtimeMobileRequest) Validate() error {
+	var v validator
+	if len(r.Amount.Currency) > 0 {
+		v.add("Amount", validateCurrency(r.Amount.Currency, Airtime))
+	}
+	v.msisdn("Account", r.Account)
+	v.amount("Amount", r.Amount)
+	v.text("Narrative", r.Narrative, true)
+	v.reference("ExternalReference", r.ExternalReference)
+	v.reference("InternalReference", r.InternalReference)
+	v.text("ProviderReferenceText", r.ProviderReferenceText, false)
+	return v.err(r.method())
+}
+
+/* Validate
+Check the request before it is sent, SendAirtimeInternalContext calls it
+*/
+func (r *SendAirtimeInternalRequest) Validate() error {
+	var v validator
+	v.amount("Amount", r.Amount)
+	v.text("Narrative", r.Narrative, true)
+	v.currency("CurrencyCode", r.CurrencyCode, Airtime)
+	if len(r.BeneficiaryAccount) == 0 {
+		v.add("BeneficiaryAccount", errRequired)
+	} else if strings.Trim(r.BeneficiaryAccount, "0123456789") != "" {
+		v.add("BeneficiaryAccount", fmt.Errorf("not an account number %q", r.BeneficiaryAccount))
+	}
+	v.email("BeneficiaryEmail", r.BeneficiaryEmail, false)
+	v.reference("InternalReference", r.InternalReference)
+	v.reference("ExternalReference", r.ExternalReference)
+	return v.err(r.method())
+}
+
+/* Validate
+Check the request before it is sent, VerifyAccountValidityContext calls it
+*/
+func (r *VerifyAccountValidityRequest) Validate() error {
+	var v validator
+	v.msisdn("Account", r.Account)
+	return v.err(r.method())
+}
@@ -0,0 +1,62 @@
+package yopay
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateFields(t *testing.T) {
+	err := (&InternalTransferRequest{
+		CurrencyCode:       UGXMTNMobileMoney,
+		BeneficiaryAccount: "100200",
+		BeneficiaryEmail:   "someone at example.com",
+		Amount:             Money{},
+	}).Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !errors.Is(err, ErrInvalidRequest) || !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("invalid transfer: %v", err)
+	}
+	fields := map[string]bool{}
+	for _, f := range verr.Fields {
+		fields[f.Field] = true
+	}
+	if len(fields) != 3 || !fields["BeneficiaryEmail"] || !fields["Narrative"] || !fields["Amount"] {
+		t.Fatalf("fields %v", err)
+	}
+
+	for _, r := range []interface{ Validate() error }{
+		&DepositFundsRequest{Account: "0772123456", Amount: Money{Minor: 100}, Narrative: "ok", ProviderReferenceText: "nul\x00"},
+		&DepositFundsRequest{Account: "0772123456", Amount: Money{Minor: 100}, Narrative: "bell\a"},
+		&DepositFundsRequest{Account: "0772123456", Amount: Money{Minor: 100}, Narrative: "ok", InstantNotificationUrl: "/notify"},
+		&DepositFundsRequest{Account: "0772123456", Amount: Money{Minor: 100}, Narrative: "ok", FailureNotificationUrl: "https://example.com/fail?a=1&amp;b=2"},
+		&WithdrawFundsRequest{Account: "0772123456", Amount: Money{Minor: 100}, Narrative: "ok", ExternalReference: "bad\xff"},
+		&WithdrawFundsRequest{Account: "0772123456", Amount: Money{Minor: 100}, Narrative: "ok", InternalReference: "\ufffe"},
+		&TransactionCheckStatusRequest{},
+		&MinistatementRequest{TransactionEntryDesignation: "ANY", StartDate: "2024-02-01 00:00:00", EndDate: "2024-01-01 00:00:00"},
+		&MinistatementRequest{TransactionEntryDesignation: "ANY", TransactionStatus: "SUCCEEDED,DONE"},
+		&SendAirtimeMobileRequest{Account: "256412123456", Amount: Money{Minor: 100}, Narrative: "ok"},
+		&VerifyAccountValidityRequest{},
+	} {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("%#v accepted", r)
+		}
+	}
+
+	valid := &DepositFundsRequest{Account: "256772123456", Amount: Money{Minor: 100}, Narrative: "multi\nline\ttab", ExternalReference: "ünicode", InstantNotificationUrl: "https://example.com/notify",
+		FailureNotificationUrl: "https://example.com/fail?note=fish%26amp;chips"}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateBeforeSending(t *testing.T) {
+	yo := newTestingApi(t)
+	yo.YoUrl = "http://127.0.0.1:1/unreachable"
+	yo.ResolveTimeouts = true
+	yo.Observer = func(e Exchange) { t.Fatalf("invalid request sent: %s", e.Request) }
+	_, err := yo.WithdrawFundsContext(context.Background(), WithdrawFundsRequest{Account: "0772123456", Amount: Money{Minor: 1000}, ExternalReference: "ref-1"})
+	if !errors.Is(err, ErrInvalidRequest) || errors.Is(err, ErrOutcomeUnknown) {
+		t.Fatalf("empty narrative accepted: %v", err)
+	}
+}
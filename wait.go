@@ -0,0 +1,101 @@
+// yopay project wait.go
+package yopay
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+/* Backoff
+Delays between the status queries of WaitForTransaction
+*/
+type Backoff struct {
+	// first delay, default 2 seconds
+	Initial time.Duration
+	// upper bound of the delay, default 30 seconds
+	Max time.Duration
+	// growth of the delay after every query, default 1.5
+	Multiplier float64
+}
+
+func (b Backoff) next(delay time.Duration) time.Duration {
+	if b.Initial <= 0 {
+		b.Initial = 2 * time.Second
+	}
+	if b.Max <= 0 {
+		b.Max = 30 * time.Second
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = 1.5
+	}
+	if delay <= 0 {
+		return b.Initial
+	}
+	delay = time.Duration(float64(delay) * b.Multiplier)
+	if delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
+
+/* TransactionResult
+Final status delivered by WaitForTransactionAsync
+*/
+type TransactionResult struct {
+	Status TransactionStatus
+	Err    error
+}
+
+/* WaitForTransaction
+Poll the status of transaction_reference with PollBackoff delays until it is SUCCEEDED or FAILED.
+Network failures are retried, gateway errors and invalid requests are returned at once.
+The query uses the DepositTransactionType of api.
+When ctx is done the last received status is returned with the ctx error
+*/
+func (api *YoAPI) WaitForTransaction(ctx context.Context, transaction_reference string) (TransactionStatus, error) {
+	req := TransactionCheckStatusRequest{
+		DepositTransactionType: api.DepositTransactionType,
+		TransactionReference:   transaction_reference,
+	}
+	if err := req.Validate(); err != nil {
+		return TransactionStatus{}, err
+	}
+	var last TransactionStatus
+	var delay time.Duration
+	for {
+		status, err := api.CheckTransactionStatusContext(ctx, req)
+		var yoerr *YoError
+		if errors.As(err, &yoerr) || errors.Is(err, ErrInvalidRequest) {
+			return status, err
+		}
+		if err == nil {
+			last = status
+			if status.TransactionStatus.IsTerminal() {
+				return status, nil
+			}
+		}
+
+		delay = api.PollBackoff.next(delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+/* WaitForTransactionAsync
+Same as WaitForTransaction, the result is delivered on the returned channel which is closed afterwards
+*/
+func (api *YoAPI) WaitForTransactionAsync(ctx context.Context, transaction_reference string) <-chan TransactionResult {
+	result := make(chan TransactionResult, 1)
+	go func() {
+		defer close(result)
+		status, err := api.WaitForTransaction(ctx, transaction_reference)
+		result <- TransactionResult{Status: status, Err: err}
+	}()
+	return result
+}
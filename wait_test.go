@@ -0,0 +1,98 @@
+package yopay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitForTransaction(t *testing.T) {
+	var queries int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := "PENDING"
+		if atomic.AddInt32(&queries, 1) >= 3 {
+			state = "SUCCEEDED"
+		}
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>OK</Status><StatusCode>0</StatusCode>`+
+			`<TransactionStatus>%s</TransactionStatus><TransactionReference>ref-1</TransactionReference></Response></AutoCreate>`, state)
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+	yo.PollBackoff = Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond}
+
+	r := <-yo.WaitForTransactionAsync(context.Background(), "ref-1")
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if r.Status.TransactionStatus != StateSucceeded || queries != 3 {
+		t.Fatalf("unexpected result %+v after %d queries", r.Status, queries)
+	}
+
+	atomic.StoreInt32(&queries, -1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	status, err := yo.WaitForTransaction(ctx, "ref-1")
+	if err != context.DeadlineExceeded || status.TransactionStatus != StatePending {
+		t.Fatalf("expected pending status with deadline error, got %q %v", status.TransactionStatus, err)
+	}
+}
+
+func TestWaitForTransactionInvalidReference(t *testing.T) {
+	var queries int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+	yo.PollBackoff = Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := yo.WaitForTransaction(context.Background(), "")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInvalidRequest) {
+			t.Fatalf("expected ErrInvalidRequest, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForTransaction did not return for an empty reference")
+	}
+	if queries != 0 {
+		t.Fatalf("invalid request reached the gateway %d times", queries)
+	}
+}
+
+func TestWaitForTransactionDepositType(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		state := "FAILED"
+		if strings.Contains(string(body), "<DepositTransactionType>PUSH</DepositTransactionType>") {
+			state = "SUCCEEDED"
+		}
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Response><Status>OK</Status><StatusCode>0</StatusCode>`+
+			`<TransactionStatus>%s</TransactionStatus><TransactionReference>ref-1</TransactionReference></Response></AutoCreate>`, state)
+	}))
+	defer srv.Close()
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+	yo.DepositTransactionType = "PUSH"
+
+	status, err := yo.WaitForTransaction(context.Background(), "ref-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.TransactionStatus != StateSucceeded {
+		t.Fatalf("DepositTransactionType not sent, status %q", status.TransactionStatus)
+	}
+}
@@ -3,13 +3,7 @@ package yopay
 
 import (
 	"bytes"
-	"crypto"
-	"crypto/rsa"
-	"crypto/sha1"
-	"crypto/tls"
-	"crypto/x509"
-	"encoding/base64"
-	"encoding/pem"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
@@ -38,6 +32,7 @@ type YoAPI struct {
 		Whether the connection to the Yo! Payments Gateway is maintained until your request is
 		fulfilled. "FALSE" maintains the connection till the request is complete.
 		Default: "FALSE"
+		Used by the methods without Context suffix only, the Context methods take it from the request.
 	*/
 	NonBlocking bool
 
@@ -46,6 +41,7 @@ type YoAPI struct {
 		Optional.
 		An External Reference is something which yourself and the beneficiary agree upon e.g. an invoice number
 		Default: Empty
+		Used by the methods without Context suffix only, the Context methods take it from the request.
 	*/
 	ExternalReference string
 
@@ -55,6 +51,7 @@ type YoAPI struct {
 		An Internal Reference is a reference code related to another Yo! Payments system transaction
 		If you are unsure about the meaning of this field, leave it as empty
 		Default: Empty
+		Used by the methods without Context suffix only, the Context methods take it from the request.
 	*/
 	InternalReference string
 
@@ -67,6 +64,7 @@ type YoAPI struct {
 		upon completion of transactions. This parameter allows you to provide some text which will
 		be appended to any such confirmatory message sent to the subscriber.
 		Default: Empty
+		Used by the methods without Context suffix only, the Context methods take it from the request.
 	*/
 	ProviderReferenceText string
 
@@ -77,9 +75,10 @@ type YoAPI struct {
 	   A payment notification will be sent to this URL.
 	   It must be properly URL encoded.
 	   e.g. http://ipnurl?key1=This+value+has+encoded+white+spaces&key2=value
-	   Any special XML Characters must be escaped or your request will fail
-	   e.g. http://ipnurl?key1=This+value+has+encoded+white+spaces&amp;key2=value
+	   Pass the plain URL, the request body is XML escaped by the client,
+	   an already escaped &amp; would reach the gateway as &amp;amp;
 	    Default: Empty
+	    Used by the methods without Context suffix only, the Context methods take it from the request.
 	*/
 	InstantNotificationUrl string
 
@@ -90,9 +89,10 @@ type YoAPI struct {
 	   A failure notification will be sent to this URL.
 	   It must be properly URL encoded.
 	   e.g. http://failureurl?key1=This+value+has+encoded+white+spaces&key2=value
-	   Any special XML Characters must be escaped or your request will fail
-	   e.g. http://failureurl?key1=This+value+has+encoded+white+spaces&amp;key2=value
+	   Pass the plain URL, the request body is XML escaped by the client,
+	   an already escaped &amp; would reach the gateway as &amp;amp;
 	   Default: Empty
+	   Used by the methods without Context suffix only, the Context methods take it from the request.
 	*/
 	FailureNotificationUrl string
 
@@ -103,6 +103,7 @@ type YoAPI struct {
 	   Contact Yo! Payments support services for clarification on the cases where this parameter
 	   is required.
 	   Default: Empty
+	   Used by the methods without Context suffix only, the Context methods take it from the request.
 	*/
 	AuthenticationSignatureBase64 string
 
@@ -113,6 +114,7 @@ type YoAPI struct {
 	    Set to "PULL" if following up on the status of a pull deposit funds transaction
 	    Default: "PULL"
 	    Options: "PULL", "PUSH"
+	    Used by the methods without Context suffix only, the Context methods take it from the request.
 	*/
 	DepositTransactionType string
 
@@ -123,43 +125,116 @@ type YoAPI struct {
 	   Options:
 	   * "https://paymentsapi1.yo.co.ug/ybs/task.php",
 	   * "https://paymentsapi2.yo.co.ug/ybs/task.php",
-	   * "https://41.220.12.206/services/yopaymentsdev/task.php" For Sandbox tests
+	   * "https://41.220.12.206/services/yopaymentsdev/task.php" For Sandbox tests,
+	     when its certificate does not verify pin it with NewHTTPClient(nil, fingerprint)
 	*/
 	YoUrl string
 
-	/** LastQuery, LastResponse, LastError - for debug
-	 */
-	LastQuery      string
-	LastResponse   string
-	LastError      string
-	LastStatusCode int
+	/* Endpoints
+	   The gateway urls with failover
+	   Optional.
+	   When set it is used instead of YoUrl. Each call goes to the first healthy endpoint,
+	   connection failures and 5xx responses mark the endpoint unhealthy and:
+	   * balance, transaction status, ministatement and account verification queries are retried
+	     on the next endpoint
+	   * deposits, withdrawals, transfers and airtime are never resent, the error is returned and
+	     the next call goes to another endpoint
+	   A set without urls fails every call with ErrNoEndpoints.
+	   e.g. api.Endpoints = NewEndpointSet(PaymentsAPI1, PaymentsAPI2)
+	   Default: nil
+	*/
+	Endpoints *EndpointSet
+
+	/* Observer
+	   Debug hook called after every exchange with the gateway
+	   Optional.
+	   The credentials are redacted from the reported request. It is called from the goroutine
+	   making the call, so it must be safe for concurrent use when the client is shared.
+	   Default: nil
+	*/
+	Observer func(Exchange)
+
+	/* PollBackoff
+	   The delays between status queries of WaitForTransaction
+	   Optional.
+	   Default: 2 seconds growing 1.5 times per query up to 30 seconds
+	*/
+	PollBackoff Backoff
+
+	/* AutoExternalReference
+	   Generate an ExternalReference for deposits and withdrawals sent without one
+	   Optional.
+	   Default: false
+	*/
+	AutoExternalReference bool
+
+	/* ResolveTimeouts
+	   Look up the outcome of deposits and withdrawals interrupted by a timeout, a dropped connection
+	   or a broken response
+	   Optional.
+	   The transaction is checked with CheckTransactionStatus by its ExternalReference before returning.
+	   A SUCCEEDED transaction is returned with a nil error, a FAILED one with a *YoError like a
+	   request the gateway answered with a failure.
+	   When the lookup fails or the transaction is still PENDING or INDETERMINATE an error matching
+	   ErrOutcomeUnknown is returned, along with the looked up response if any. It is safe to
+	   resend the request with the same ExternalReference as the gateway rejects duplicates.
+	   Default: false
+	*/
+	ResolveTimeouts bool
+
+	/* NotificationKeys
+	   The keys verifying payment and failure notification signatures
+	   Optional.
+	   A notification is accepted when any of the keys verifies it, so during a Yo! key rotation
+	   both the old and the new key can be trusted. See ParseKeysPEM and LoadKeysFile.
+	   Default: nil, the Yo! certificate built into the package
+	*/
+	NotificationKeys KeyProvider
+
+	/* CheckKeyValidity
+	   Ignore notification certificates outside their validity dates
+	   Optional.
+	   Bare public keys have no dates and are always used.
+	   Default: false, the built-in Yo! certificate expired in August 2023
+	*/
+	CheckKeyValidity bool
 
 	/* Timeout
 	Default: 180
 	*/
 	QueryTimeout int
+
+	/* HTTPClient
+	   The http client used for gateway queries
+	   Optional.
+	   Set it to supply your own RoundTripper or TLS settings, use NewHTTPClient to verify the
+	   gateway against a custom CA pool or pinned certificate fingerprints.
+	   The client is reused for all calls so connections are kept alive.
+	   Default: nil, a shared client verifying the gateway certificate against the system roots
+	*/
+	HTTPClient *http.Client
 }
 
 type DepositResponse struct {
-	Status                    string `xml:"Status"`
-	StatusCode                string `xml:"StatusCode"`
-	StatusMessage             string `xml:"StatusMessage"`
-	TransactionStatus         string `xml:"TransactionStatus,omitempty"`
-	ErrorMessageCode          string `xml:"ErrorMessageCode,omitempty"`
-	ErrorMessage              string `xml:"ErrorMessage,omitempty"`
-	TransactionReference      string `xml:"TransactionReference,omitempty"`
-	MNOTransactionReferenceId string `xml:"MNOTransactionReferenceId,omitempty"`
-	IssuedReceiptNumber       string `xml:"IssuedReceiptNumber,omitempty"`
+	Status                    string           `xml:"Status"`
+	StatusCode                string           `xml:"StatusCode"`
+	StatusMessage             string           `xml:"StatusMessage"`
+	TransactionStatus         TransactionState `xml:"TransactionStatus,omitempty"`
+	ErrorMessageCode          string           `xml:"ErrorMessageCode,omitempty"`
+	ErrorMessage              string           `xml:"ErrorMessage,omitempty"`
+	TransactionReference      string           `xml:"TransactionReference,omitempty"`
+	MNOTransactionReferenceId string           `xml:"MNOTransactionReferenceId,omitempty"`
+	IssuedReceiptNumber       string           `xml:"IssuedReceiptNumber,omitempty"`
 }
 
 type TransactionStatus struct {
 	DepositResponse
 
-	Amount                    string `xml:"Amount,omitempty"`
-	AmountFormatted           string `xml:"AmountFormatted,omitempty"`
-	CurrencyCode              string `xml:"CurrencyCode,omitempty"`
-	TransactionInitiationDate string `xml:"TransactionInitiationDate,omitempty"`
-	TransactionCompletionDate string `xml:"TransactionCompletionDate,omitempty"`
+	Amount                    string       `xml:"Amount,omitempty"`
+	AmountFormatted           string       `xml:"AmountFormatted,omitempty"`
+	CurrencyCode              CurrencyCode `xml:"CurrencyCode,omitempty"`
+	TransactionInitiationDate string       `xml:"TransactionInitiationDate,omitempty"`
+	TransactionCompletionDate string       `xml:"TransactionCompletionDate,omitempty"`
 }
 
 type BalanceResponse struct {
@@ -167,50 +242,59 @@ type BalanceResponse struct {
 	StatusCode       string `xml:"StatusCode"`
 	StatusMessage    string `xml:"StatusMessage"`
 	ErrorMessageCode string `xml:"ErrorMessageCode,omitempty"`
-	ErrorMessage     string `xml:"TransactionReference,omitempty"`
+	ErrorMessage     string `xml:"ErrorMessage,omitempty"`
 	Balance          struct {
-		Currency []struct {
-			Code    string `xml:"Code"`
-			Balance string `xml:"Balance"`
-		} `xml:"Currency"`
+		Currency []CurrencyBalance `xml:"Currency"`
 	} `xml:"Balance"`
 }
 
+// balance of one currency in BalanceResponse
+type CurrencyBalance struct {
+	Code    CurrencyCode `xml:"Code"`
+	Balance string       `xml:"Balance"`
+}
+
 type MinistatementResponse struct {
 	Status               string `xml:"Status"`
 	StatusCode           string `xml:"StatusCode"`
+	StatusMessage        string `xml:"StatusMessage"`
 	ErrorMessageCode     string `xml:"ErrorMessageCode,omitempty"`
-	ErrorMessage         string `xml:"TransactionReference,omitempty"`
+	ErrorMessage         string `xml:"ErrorMessage,omitempty"`
 	TotalTransactions    string `xml:"TotalTransactions"`
 	ReturnedTransactions string `xml:"ReturnedTransactions"`
 	Transactions         struct {
-		Transaction []struct {
-			TransactionSystemId                string `xml:"TransactionSystemId"`
-			TransactionReference               string `xml:"TransactionReference"`
-			TransactionStatus                  string `xml:"TransactionStatus"`
-			InitiationDate                     string `xml:"InitiationDate"`
-			CompletionDate                     string `xml:"CompletionDate"`
-			NarrativeBase64                    string `xml:"NarrativeBase64"`
-			Currency                           string `xml:"Currency"`
-			Amount                             string `xml:"Amount"`
-			Balance                            string `xml:"Balance"`
-			GeneralType                        string `xml:"GeneralType"`
-			DetailedType                       string `xml:"DetailedType"`
-			BeneficiaryMsisdn                  string `xml:"BeneficiaryMsisdn"`
-			BeneficiaryBase64                  string `xml:"BeneficiaryBase64"`
-			SenderMsisdn                       string `xml:"SenderMsisdn"`
-			SenderBase64                       string `xml:"SenderBase64"`
-			Base64TransactionExternalReference string `xml:"Base64TransactionExternalReference"`
-			TransactionEntryDesignation        string `xml:"TransactionEntryDesignation"`
-		} `xml:"Transaction"`
+		Transaction []StatementTransaction `xml:"Transaction"`
 	} `xml:"Transactions"`
 }
 
+// transaction in MinistatementResponse
+type StatementTransaction struct {
+	TransactionSystemId                string           `xml:"TransactionSystemId"`
+	TransactionReference               string           `xml:"TransactionReference"`
+	TransactionStatus                  TransactionState `xml:"TransactionStatus"`
+	InitiationDate                     string           `xml:"InitiationDate"`
+	CompletionDate                     string           `xml:"CompletionDate"`
+	NarrativeBase64                    string           `xml:"NarrativeBase64"`
+	Currency                           CurrencyCode     `xml:"Currency"`
+	Amount                             string           `xml:"Amount"`
+	Balance                            string           `xml:"Balance"`
+	GeneralType                        string           `xml:"GeneralType"`
+	DetailedType                       string           `xml:"DetailedType"`
+	BeneficiaryMsisdn                  string           `xml:"BeneficiaryMsisdn"`
+	BeneficiaryBase64                  string           `xml:"BeneficiaryBase64"`
+	SenderMsisdn                       string           `xml:"SenderMsisdn"`
+	SenderBase64                       string           `xml:"SenderBase64"`
+	Base64TransactionExternalReference string           `xml:"Base64TransactionExternalReference"`
+	TransactionEntryDesignation        string           `xml:"TransactionEntryDesignation"`
+}
+
 type VerifyAccountResponse struct {
-	Response struct {
-		Status string `xml:"Status"`
-		Valid  string `xml:"Valid"`
-	} `xml:"Response"`
+	Status           string `xml:"Status"`
+	StatusCode       string `xml:"StatusCode"`
+	StatusMessage    string `xml:"StatusMessage"`
+	ErrorMessageCode string `xml:"ErrorMessageCode,omitempty"`
+	ErrorMessage     string `xml:"ErrorMessage,omitempty"`
+	Valid            string `xml:"Valid"`
 }
 
 type PaymentNotificationResponse struct {
@@ -221,12 +305,16 @@ type PaymentNotificationResponse struct {
 	NetworkRef  string
 	ExternalRef string
 	Msisdn      string
+	// set by NotificationHandler when the notification was processed before
+	Duplicate bool
 }
 
 type PaymentFailureNotificationResponse struct {
 	Verified                   bool
 	FailedTransactionReference string
 	TransactionInitDate        string
+	// set by NotificationHandler when the notification was processed before
+	Duplicate bool
 }
 
 const pub_crt string = `-----BEGIN CERTIFICATE-----
@@ -265,7 +353,7 @@ func NewYoApi(Username, Password string) YoAPI {
 	yoapi := YoAPI{
 		Username:               Username,
 		Password:               Password,
-		YoUrl:                  "https://paymentsapi1.yo.co.ug/ybs/task.php",
+		YoUrl:                  PaymentsAPI1,
 		DepositTransactionType: "PULL",
 		NonBlocking:            false,
 		QueryTimeout:           180}
@@ -274,141 +362,108 @@ func NewYoApi(Username, Password string) YoAPI {
 }
 
 func (api *YoAPI) GetXmlResponse(xmlbody string) ([]byte, error) {
-	api.LastQuery = xmlbody
-	api.LastError = ""
-	api.LastResponse = ""
-	api.LastStatusCode = 0
-	var result []byte
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	timeout := time.Duration(time.Duration(api.QueryTimeout) * time.Second)
-	client := &http.Client{Timeout: timeout, Transport: tr}
-	req, err := http.NewRequest("POST", api.YoUrl, bytes.NewBuffer([]byte(xmlbody)))
-	if err != nil {
-		fmt.Println(err)
-		return result, err
-	}
-	req.Header.Add("Content-Type", "text/xml; charset=utf-8")
-	resp, err := client.Do(req)
-	if err != nil {
-		api.LastError = fmt.Sprintf("do quqey: %v", err)
-		fmt.Println(api.LastError)
-		return result, err
-	}
-	defer resp.Body.Close()
-	body, _ := ioutil.ReadAll(resp.Body)
-	//fmt.Printf("query response: %s", body)
-
-	api.LastStatusCode = resp.StatusCode
-	api.LastResponse = string(body)
-	if resp.StatusCode != 200 {
-		api.LastError = fmt.Sprintf("Wrong xml response status %d %s", resp.StatusCode, resp.Status)
-		return result, fmt.Errorf(api.LastError)
-	}
-	//fmt.Println("response Status:", resp.Status)
-	//fmt.Println("response Headers:", resp.Header)
-
-	return body, nil
+	return api.GetXmlResponseContext(context.Background(), xmlbody)
 }
 
-// small helper for request body
-func (api *YoAPI) createXml(method string, ext string) string {
-	return fmt.Sprintf(
-		`<?xml version="1.0" encoding="UTF-8"?><AutoCreate><Request><APIUsername>%s</APIUsername><APIPassword>%s</APIPassword><Method>%s</Method>%s</Request></AutoCreate>`,
-		api.Username, api.Password, method, ext,
-	)
+/* GetXmlResponseContext
+Send xmlbody to the gateway, the ctx deadline and cancellation apply to the whole exchange
+together with QueryTimeout.
+The request is never resent to another endpoint, see Endpoints
+*/
+func (api *YoAPI) GetXmlResponseContext(ctx context.Context, xmlbody string) ([]byte, error) {
+	return api.send(ctx, "", xmlbody)
 }
 
-func (api *YoAPI) xmlInternalReference() string {
-	if len(api.InternalReference) > 0 {
-		return "<InternalReference>" + api.InternalReference + "</InternalReference>"
+// query marshals req and sends it
+func (api *YoAPI) query(ctx context.Context, req request) ([]byte, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
-	return ""
-}
-
-func (api *YoAPI) xmlExternalReference() string {
-	if len(api.ExternalReference) > 0 {
-		return "<ExternalReference>" + api.ExternalReference + "</ExternalReference>"
+	xmlbody, err := api.marshalRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return api.send(ctx, req.method(), string(xmlbody))
+}
+
+// send tries the endpoints in health order, moving to the next one only for methods which are safe to resend
+func (api *YoAPI) send(ctx context.Context, method string, xmlbody string) ([]byte, error) {
+	urls := []string{api.YoUrl}
+	if api.Endpoints != nil {
+		urls = api.Endpoints.order()
+	}
+	if len(urls) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	var err error
+	for _, url := range urls {
+		var body []byte
+		var status int
+		body, status, err = api.exchange(ctx, method, url, xmlbody)
+		if err == nil {
+			if api.Endpoints != nil {
+				api.Endpoints.markHealthy(url)
+			}
+			return body, nil
+		}
+		if ctx.Err() != nil || (status != 0 && status < 500) {
+			return nil, err
+		}
+		if api.Endpoints != nil {
+			api.Endpoints.markFailed(url)
+		}
+		if !retryableMethods[method] {
+			return nil, err
+		}
 	}
-	return ""
+	return nil, err
 }
 
-func (api *YoAPI) xmlNonBlocking() string {
-	if api.NonBlocking {
-		return "<NonBlocking>TRUE</NonBlocking>"
+// exchange posts xmlbody to url, status is 0 when no response was received
+func (api *YoAPI) exchange(ctx context.Context, method string, url string, xmlbody string) ([]byte, int, error) {
+	started := time.Now()
+	body, status, err := api.post(ctx, url, xmlbody)
+	if api.Observer != nil {
+		api.Observer(Exchange{
+			Method:     method,
+			Url:        url,
+			Request:    redactCredentials(xmlbody),
+			Response:   string(body),
+			StatusCode: status,
+			Duration:   time.Since(started),
+			Err:        err,
+		})
 	}
-	return ""
-}
-
-func (api *YoAPI) xmlProviderReferenceText() string {
-	if len(api.ProviderReferenceText) > 0 {
-		return "<ProviderReferenceText>" + api.ProviderReferenceText + "</ProviderReferenceText>"
+	if err != nil {
+		return nil, status, err
 	}
-	return ""
+	return body, status, nil
 }
 
-func (api *YoAPI) xmlInstantNotificationUrl() string {
-	if len(api.InstantNotificationUrl) > 0 {
-		return "<InstantNotificationUrl>" + api.InstantNotificationUrl + "</InstantNotificationUrl>"
+func (api *YoAPI) post(ctx context.Context, url string, xmlbody string) ([]byte, int, error) {
+	if api.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(api.QueryTimeout)*time.Second)
+		defer cancel()
 	}
-	return ""
-}
-
-func (api *YoAPI) xmlFailureNotificationUrl() string {
-	if len(api.FailureNotificationUrl) > 0 {
-		return "<FailureNotificationUrl>" + api.FailureNotificationUrl + "</FailureNotificationUrl>"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(xmlbody)))
+	if err != nil {
+		return nil, 0, err
 	}
-	return ""
-}
-
-func (api *YoAPI) xmlAuthenticationSignatureBase64() string {
-	if len(api.AuthenticationSignatureBase64) > 0 {
-		return "<AuthenticationSignatureBase64>" + api.AuthenticationSignatureBase64 + "</AuthenticationSignatureBase64>"
+	req.Header.Add("Content-Type", "text/xml; charset=utf-8")
+	resp, err := api.httpClient().Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("do query: %w", err)
 	}
-	return ""
-}
-
-func (api *YoAPI) xmlDepositTransactionType() string {
-	return "<DepositTransactionType>" + api.DepositTransactionType + "</DepositTransactionType>"
-}
-
-func (api *YoAPI) xmlAccount(account string) string {
-	return "<Account>" + account + "</Account>"
-}
-
-func (api *YoAPI) xmlAmount(amount int64) string {
-	return fmt.Sprintf("<Amount>%d</Amount>", amount)
-}
-
-func (api *YoAPI) xmlNarrative(narrative string) string {
-	return "<Narrative>" + narrative + "</Narrative>"
-}
-
-func (api *YoAPI) xmlCurrencyCode(currency_code string) string {
-	return "<CurrencyCode>" + currency_code + "</CurrencyCode>"
-}
-
-func (api *YoAPI) xmlBeneficiaryAccount(beneficiary_account int64) string {
-	return "<BeneficiaryAccount>" + fmt.Sprint(beneficiary_account) + "</BeneficiaryAccount>"
-}
-
-func (api *YoAPI) xmlBeneficiaryEmail(beneficiary_email string) string {
-	return "<BeneficiaryEmail>" + beneficiary_email + "</BeneficiaryEmail>"
-}
-
-func (api *YoAPI) xmlTransactionReference(transaction_reference string) string {
-	if len(transaction_reference) > 0 {
-		return "<TransactionReference>" + transaction_reference + "</TransactionReference>"
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return body, 0, fmt.Errorf("read response: %w", err)
 	}
-	return ""
-}
-
-func (api *YoAPI) xmlPrivateTransactionReference(private_transaction_reference string) string {
-	if len(private_transaction_reference) > 0 {
-		return "<PrivateTransactionReference>" + private_transaction_reference + "</PrivateTransactionReference>"
+	if resp.StatusCode != 200 {
+		return body, resp.StatusCode, fmt.Errorf("Wrong xml response status %d %s", resp.StatusCode, resp.Status)
 	}
-	return ""
+	return body, resp.StatusCode, nil
 }
 
 /* DepositFunds
@@ -418,36 +473,54 @@ func (api *YoAPI) xmlPrivateTransactionReference(private_transaction_reference s
    your request to transfer funds out of their account and requests them to authorize the
    request to complete the transaction.
    This request is not supported by all mobile money operator networks
-   * msisdn the mobile money phone number in the format 256772123456
-   * amount the amount of money to deposit into your account (floats are supported)
+   * msisdn the mobile money phone number in the format 256772123456, local and spaced formats are normalized, see ParseMSISDN
+   * amount the amount of money to deposit into your account in whole units, use the Context form with Money for fractions
    * narrative the reason for the mobile money user to deposit funds
 
 */
 func (api *YoAPI) DepositFunds(msisdn string, amount int64, narrative string) (DepositResponse, error) {
+	money, err := NewMoney(amount, "")
+	if err != nil {
+		return DepositResponse{}, err
+	}
+	return api.DepositFundsContext(context.Background(), DepositFundsRequest{
+		Account:                       msisdn,
+		Amount:                        money,
+		Narrative:                     narrative,
+		ExternalReference:             api.ExternalReference,
+		InternalReference:             api.InternalReference,
+		ProviderReferenceText:         api.ProviderReferenceText,
+		NonBlocking:                   Bool(api.NonBlocking),
+		InstantNotificationUrl:        api.InstantNotificationUrl,
+		FailureNotificationUrl:        api.FailureNotificationUrl,
+		AuthenticationSignatureBase64: api.AuthenticationSignatureBase64,
+	})
+}
+
+/* DepositFundsContext
+Same as DepositFunds with the parameters taken from req instead of the shared YoAPI fields.
+The ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) DepositFundsContext(ctx context.Context, req DepositFundsRequest) (DepositResponse, error) {
 	type Resp struct {
 		XMLName  xml.Name        `xml:"AutoCreate"`
 		Response DepositResponse `xml:"Response"`
 	}
-	xmlbody := api.xmlAccount(msisdn)
-	xmlbody += api.xmlAmount(amount)
-	xmlbody += api.xmlNarrative(narrative)
-	xmlbody += api.xmlExternalReference()
-	xmlbody += api.xmlInternalReference()
-	xmlbody += api.xmlProviderReferenceText()
-	xmlbody += api.xmlNonBlocking()
-	xmlbody += api.xmlInstantNotificationUrl()
-	xmlbody += api.xmlFailureNotificationUrl()
-	xmlbody += api.xmlAuthenticationSignatureBase64()
-	xmlbody = api.createXml("acdepositfunds", xmlbody)
+	if api.AutoExternalReference && len(req.ExternalReference) == 0 {
+		req.ExternalReference = newExternalReference()
+	}
 	var response DepositResponse
+	req.Account = normalizeMSISDN(req.Account)
 	var r Resp
-	resp, err := api.GetXmlResponse(xmlbody)
+	resp, err := api.query(ctx, &req)
+	if err == nil {
+		err = xml.Unmarshal(resp, &r)
+	}
 	if err != nil {
-		return response, err
+		return api.resolveOutcome(ctx, req.ExternalReference, err)
 	}
-	err = xml.Unmarshal(resp, &r)
 	response = r.Response
-	return response, err
+	return response, response.yoError()
 }
 
 /* TransactionCheckStatus
@@ -458,28 +531,42 @@ It can also be used to check on any other transaction on the system.
 * private_transaction_reference The External Reference that was used to carry out a transaction
 */
 func (api *YoAPI) CheckTransactionStatus(transaction_reference string, private_transaction_reference string) (TransactionStatus, error) {
+	return api.CheckTransactionStatusContext(context.Background(), TransactionCheckStatusRequest{
+		DepositTransactionType:      api.DepositTransactionType,
+		TransactionReference:        transaction_reference,
+		PrivateTransactionReference: private_transaction_reference,
+	})
+}
+
+/* CheckTransactionStatusContext
+Same as CheckTransactionStatus with the parameters taken from req instead of the shared YoAPI fields.
+The ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) CheckTransactionStatusContext(ctx context.Context, req TransactionCheckStatusRequest) (TransactionStatus, error) {
 	type Resp struct {
 		XMLName  xml.Name          `xml:"AutoCreate"`
 		Response TransactionStatus `xml:"Response"`
 	}
-	xmlbody := api.xmlDepositTransactionType()
-	xmlbody += api.xmlTransactionReference(transaction_reference)
-	xmlbody += api.xmlPrivateTransactionReference(private_transaction_reference)
-	xmlbody = api.createXml("actransactioncheckstatus", xmlbody)
+	if len(req.DepositTransactionType) == 0 {
+		req.DepositTransactionType = "PULL"
+	}
 	var response TransactionStatus
-	resp, err := api.GetXmlResponse(xmlbody)
+	resp, err := api.query(ctx, &req)
 	if err != nil {
 		return response, err
 	}
 	var r Resp
 	err = xml.Unmarshal(resp, &r)
 	response = r.Response
+	if err == nil {
+		err = response.yoError()
+	}
 	return response, err
 }
 
 /* InternalTransfer
    Transfer funds from your Payment Account to another Yo! Payments Account
-   currency_code Options
+   currency_code Options, see Currencies
    * "UGX-MTNMM" -> Uganda Shillings - MTN Mobile Money
    * "UGX-MTNAT" -> Uganda Shillings - MTN Airtime
    * "UGX-WTLAT" -> Uganda Shillings - Warid Airtime
@@ -491,29 +578,46 @@ func (api *YoAPI) CheckTransactionStatus(transaction_reference string, private_t
    narrative Textual narrative about the transaction
 */
 func (api *YoAPI) InternalTransfer(currency_code string, amount int64, beneficiary_account string, beneficiary_email string, narrative string) (DepositResponse, error) {
+	money, err := NewMoney(amount, CurrencyCode(currency_code))
+	if err != nil {
+		return DepositResponse{}, err
+	}
+	return api.InternalTransferContext(context.Background(), InternalTransferRequest{
+		CurrencyCode:       CurrencyCode(currency_code),
+		BeneficiaryAccount: beneficiary_account,
+		BeneficiaryEmail:   beneficiary_email,
+		Narrative:          narrative,
+		Amount:             money,
+		InternalReference:  api.InternalReference,
+		ExternalReference:  api.ExternalReference,
+	})
+}
+
+/* InternalTransferContext
+Same as InternalTransfer with the parameters taken from req instead of the shared YoAPI fields.
+The ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) InternalTransferContext(ctx context.Context, req InternalTransferRequest) (DepositResponse, error) {
 	type Resp struct {
 		XMLName  xml.Name        `xml:"AutoCreate"`
 		Response DepositResponse `xml:"Response"`
 	}
-	xmlbody := fmt.Sprintf(
-		`<CurrencyCode>%s</CurrencyCode>
-    	<BeneficiaryAccount>%s</BeneficiaryAccount>
-    	<BeneficiaryEmail>%s</BeneficiaryEmail>`,
-		currency_code, beneficiary_account, beneficiary_email)
-	xmlbody += api.xmlNarrative(narrative)
-	xmlbody += api.xmlAmount(amount)
-	xmlbody += api.xmlInternalReference()
-	xmlbody += api.xmlExternalReference()
-	xmlbody = api.createXml("acinternaltransfer", xmlbody)
 
 	var response DepositResponse
-	resp, err := api.GetXmlResponse(xmlbody)
+	var err error
+	if req.CurrencyCode, err = currencyOf(req.CurrencyCode, req.Amount); err != nil {
+		return response, err
+	}
+	resp, err := api.query(ctx, &req)
 	if err != nil {
 		return response, err
 	}
 	var r Resp
 	err = xml.Unmarshal(resp, &r)
 	response = r.Response
+	if err == nil {
+		err = response.yoError()
+	}
 	return response, err
 }
 
@@ -522,24 +626,34 @@ Get the current balance of your Yo! Payments Account
 Returned array contains an array of balances (including airtime)
 */
 func (api *YoAPI) GetAcctBalance() (BalanceResponse, error) {
+	return api.GetAcctBalanceContext(context.Background())
+}
+
+/* GetAcctBalanceContext
+Same as GetAcctBalance, the ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) GetAcctBalanceContext(ctx context.Context) (BalanceResponse, error) {
 	type Resp struct {
 		XMLName  xml.Name        `xml:"AutoCreate"`
 		Response BalanceResponse `xml:"Response"`
 	}
-	xmlbody := api.createXml("acacctbalance", "")
 	var response BalanceResponse
-	resp, err := api.GetXmlResponse(xmlbody)
+	resp, err := api.query(ctx, &acctBalanceRequest{})
 	if err != nil {
 		return response, err
 	}
 	var r Resp
 	err = xml.Unmarshal(resp, &r)
 	response = r.Response
+	if err == nil {
+		err = response.yoError()
+	}
 	return response, err
 }
 
 /* GetMinistatement
 Return an array of transactions which were carried out on your account for a certain period of time
+QueryMinistatement takes the filter as a typed StatementQuery
 * start_date format YYYY-MM-DD HH:MM:SS
 * end_date  format YYYY-MM-DD HH:MM:SS
 * transaction_status
@@ -548,7 +662,8 @@ Return an array of transactions which were carried out on your account for a cer
   - "INDETERMINATE"
   - "SUCCEEDED"
   - "FAILED,SUCCEEDED" (comma separated)
-* currency_code
+
+* currency_code, see Currencies
   	- "UGX-MTNMM" -> Uganda Shillings - MTN Mobile Money
  	- "UGX-WARIDMM" -> Uganda Shillings - Airtel Money
   	- "UGX-MTNAT" -> Uganda Shillings - MTN Airtime
@@ -563,80 +678,94 @@ Return an array of transactions which were carried out on your account for a cer
  external_reference Filter using this external_reference
 */
 func (api *YoAPI) GetMinistatement(start_date, end_date, transaction_status, currency_code, result_set_limit, transaction_entry_designation, external_reference string) (MinistatementResponse, error) {
+	return api.GetMinistatementContext(context.Background(), MinistatementRequest{
+		TransactionEntryDesignation: transaction_entry_designation,
+		StartDate:                   start_date,
+		EndDate:                     end_date,
+		TransactionStatus:           transaction_status,
+		CurrencyCode:                CurrencyCode(currency_code),
+		ResultSetLimit:              result_set_limit,
+		ExternalReference:           external_reference,
+	})
+}
+
+/* GetMinistatementContext
+Same as GetMinistatement with the parameters taken from req instead of the shared YoAPI fields.
+The ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) GetMinistatementContext(ctx context.Context, req MinistatementRequest) (MinistatementResponse, error) {
 	type Resp struct {
 		XMLName  xml.Name              `xml:"AutoCreate"`
 		Response MinistatementResponse `xml:"Response"`
 	}
-	if len(transaction_entry_designation) == 0 {
-		transaction_entry_designation = "ANY"
-	}
-	var xmlbody string
-	xmlbody += "<TransactionEntryDesignation>" + transaction_entry_designation + "</TransactionEntryDesignation>"
-	if len(start_date) > 0 {
-		xmlbody += "<StartDate>" + start_date + "</StartDate>"
-	}
-	if len(end_date) > 0 {
-		xmlbody += "<EndDate>" + end_date + "</EndDate>"
-	}
-	if len(transaction_status) > 0 {
-		xmlbody += "<TransactionStatus>" + transaction_status + "</TransactionStatus>"
-	}
-	if len(currency_code) > 0 {
-		xmlbody += "<CurrencyCode>" + currency_code + "</CurrencyCode>"
-	}
-	if len(result_set_limit) > 0 {
-		xmlbody += "<ResultSetLimit>" + result_set_limit + "</ResultSetLimit>"
-	}
-	if len(external_reference) > 0 {
-		xmlbody += "<ExternalReference>" + external_reference + "</ExternalReference>"
+	if len(req.TransactionEntryDesignation) == 0 {
+		req.TransactionEntryDesignation = "ANY"
 	}
 
-	xmlbody = api.createXml("acgetministatement", xmlbody)
 	var response MinistatementResponse
-	resp, err := api.GetXmlResponse(xmlbody)
+	resp, err := api.query(ctx, &req)
 	if err != nil {
 		return response, err
 	}
 	var r Resp
 	err = xml.Unmarshal(resp, &r)
 	response = r.Response
+	if err == nil {
+		err = response.yoError()
+	}
 	return response, err
 }
 
 /*  SendAirtimeMobile
 Send airtime to a mobile phone user
-- msisdn the mobile phone number in the format 256772123456
+- msisdn the mobile phone number in the format 256772123456, see ParseMSISDN
 - amount the amount of airtime to be sent to the mobile user
 - narrative textual narrative about the transfer
 */
 func (api *YoAPI) SendAirtimeMobile(msisdn string, amount int64, narrative string) (DepositResponse, error) {
+	money, err := NewMoney(amount, "")
+	if err != nil {
+		return DepositResponse{}, err
+	}
+	return api.SendAirtimeMobileContext(context.Background(), SendAirtimeMobileRequest{
+		Account:               msisdn,
+		Amount:                money,
+		Narrative:             narrative,
+		NonBlocking:           Bool(api.NonBlocking),
+		ExternalReference:     api.ExternalReference,
+		InternalReference:     api.InternalReference,
+		ProviderReferenceText: api.ProviderReferenceText,
+	})
+}
+
+/* SendAirtimeMobileContext
+Same as SendAirtimeMobile with the parameters taken from req instead of the shared YoAPI fields.
+The ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) SendAirtimeMobileContext(ctx context.Context, req SendAirtimeMobileRequest) (DepositResponse, error) {
 	type Resp struct {
 		XMLName  xml.Name        `xml:"AutoCreate"`
 		Response DepositResponse `xml:"Response"`
 	}
-	xmlbody := api.xmlAccount(msisdn)
-	xmlbody += api.xmlAmount(amount)
-	xmlbody += api.xmlNarrative(narrative)
-	xmlbody += api.xmlNonBlocking()
-	xmlbody += api.xmlExternalReference()
-	xmlbody += api.xmlInternalReference()
-	xmlbody += api.xmlProviderReferenceText()
-	xmlbody = api.createXml("acsendairtimemobile", xmlbody)
 
 	var response DepositResponse
-	resp, err := api.GetXmlResponse(xmlbody)
+	req.Account = normalizeMSISDN(req.Account)
+	resp, err := api.query(ctx, &req)
 	if err != nil {
 		return response, err
 	}
 	var r Resp
 	err = xml.Unmarshal(resp, &r)
 	response = r.Response
+	if err == nil {
+		err = response.yoError()
+	}
 	return response, err
 }
 
 /* SendAirtimeInternal
 Send airtime from your Yo! Payments account to another Yo! Payments user account
-currency_code
+currency_code, an airtime code of Currencies
 * "UGX-MTNAT" -> Uganda Shillings - MTN Airtime
 * "UGX-WTLAT" -> Uganda Shillings - Warid Airtime
 * "UGX-OULAT" -> Uganda Shillings - Orange Airtime
@@ -647,55 +776,82 @@ beneficiary_email
 narrative textual narrative about the transfer
 */
 func (api *YoAPI) SendAirtimeInternal(currency_code string, amount int64, beneficiary_account int64, beneficiary_email string, narrative string) (DepositResponse, error) {
+	money, err := NewMoney(amount, CurrencyCode(currency_code))
+	if err != nil {
+		return DepositResponse{}, err
+	}
+	return api.SendAirtimeInternalContext(context.Background(), SendAirtimeInternalRequest{
+		Amount:             money,
+		Narrative:          narrative,
+		CurrencyCode:       CurrencyCode(currency_code),
+		BeneficiaryAccount: fmt.Sprint(beneficiary_account),
+		BeneficiaryEmail:   beneficiary_email,
+		InternalReference:  api.InternalReference,
+		ExternalReference:  api.ExternalReference,
+	})
+}
+
+/* SendAirtimeInternalContext
+Same as SendAirtimeInternal with the parameters taken from req instead of the shared YoAPI fields.
+The ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) SendAirtimeInternalContext(ctx context.Context, req SendAirtimeInternalRequest) (DepositResponse, error) {
 	type Resp struct {
 		XMLName  xml.Name        `xml:"AutoCreate"`
 		Response DepositResponse `xml:"Response"`
 	}
-	xmlbody := api.xmlAmount(amount)
-	xmlbody += api.xmlNarrative(narrative)
-	xmlbody += api.xmlInternalReference()
-	xmlbody += api.xmlCurrencyCode(currency_code)
-	xmlbody += api.xmlBeneficiaryAccount(beneficiary_account)
-	xmlbody += api.xmlBeneficiaryEmail(beneficiary_email)
-	xmlbody += api.xmlInternalReference()
-	xmlbody += api.xmlExternalReference()
-	xmlbody = api.createXml("acsendairtimeinternal", xmlbody)
 
 	var response DepositResponse
-	resp, err := api.GetXmlResponse(xmlbody)
+	var err error
+	if req.CurrencyCode, err = currencyOf(req.CurrencyCode, req.Amount); err != nil {
+		return response, err
+	}
+	resp, err := api.query(ctx, &req)
 	if err != nil {
 		return response, err
 	}
 	var r Resp
 	err = xml.Unmarshal(resp, &r)
 	response = r.Response
+	if err == nil {
+		err = response.yoError()
+	}
 	return response, err
 }
 
 /* VerifyAccountValidity
 Verify the validity of a given mobile money account
- msisdn the mobile phone number in the format 256772123456
+ msisdn the mobile phone number in the format 256772123456, see ParseMSISDN
  return boolean true if valid
 */
 func (api *YoAPI) VerifyAccountValidity(msisdn string) (bool, error) {
+	return api.VerifyAccountValidityContext(context.Background(), msisdn)
+}
+
+/* VerifyAccountValidityContext
+Same as VerifyAccountValidity, the ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) VerifyAccountValidityContext(ctx context.Context, msisdn string) (bool, error) {
 	type Resp struct {
 		XMLName  xml.Name              `xml:"AutoCreate"`
 		Response VerifyAccountResponse `xml:"Response"`
 	}
-	xmlbody := api.xmlAccount(msisdn)
-	xmlbody = api.createXml("acverifyaccountvalidity", xmlbody)
 
 	var isvalid bool = false
 	var response VerifyAccountResponse
-	resp, err := api.GetXmlResponse(xmlbody)
+	req := &VerifyAccountValidityRequest{Account: normalizeMSISDN(msisdn)}
+	resp, err := api.query(ctx, req)
 	if err != nil {
 		return isvalid, err
 	}
 	var r Resp
 	err = xml.Unmarshal(resp, &r)
 	response = r.Response
-	if response.Response.Status == "OK" {
-		if response.Response.Valid == "TRUE" {
+	if err == nil {
+		err = response.yoError()
+	}
+	if response.Status == "OK" {
+		if response.Valid == "TRUE" {
 			isvalid = true
 		} else {
 			isvalid = false
@@ -711,33 +867,51 @@ func (api *YoAPI) VerifyAccountValidity(msisdn string) (bool, error) {
    withdrawal of funds from your account.
    This request is not supported by all mobile money operator networks
    This request requires permission that is granted by the issuance of an API Access Letter
-   * msisdn the mobile money phone number in the format 256772123456
-   * amount the amount of money to withdraw from your account (floats are supported)
+   * msisdn the mobile money phone number in the format 256772123456, local and spaced formats are normalized, see ParseMSISDN
+   * amount the amount of money to withdraw from your account in whole units, use the Context form with Money for fractions
    * narrative the reason for withdrawal of funds from your account
 */
 func (api *YoAPI) WithdrawFunds(msisdn string, amount int64, narrative string) (DepositResponse, error) {
+	money, err := NewMoney(amount, "")
+	if err != nil {
+		return DepositResponse{}, err
+	}
+	return api.WithdrawFundsContext(context.Background(), WithdrawFundsRequest{
+		NonBlocking:           Bool(api.NonBlocking),
+		Account:               msisdn,
+		Amount:                money,
+		Narrative:             narrative,
+		ExternalReference:     api.ExternalReference,
+		InternalReference:     api.InternalReference,
+		ProviderReferenceText: api.ProviderReferenceText,
+	})
+}
+
+/* WithdrawFundsContext
+Same as WithdrawFunds with the parameters taken from req instead of the shared YoAPI fields.
+The ctx deadline and cancellation apply to the gateway call
+*/
+func (api *YoAPI) WithdrawFundsContext(ctx context.Context, req WithdrawFundsRequest) (DepositResponse, error) {
 	type Resp struct {
 		XMLName  xml.Name        `xml:"AutoCreate"`
 		Response DepositResponse `xml:"Response"`
 	}
-	xmlbody := api.xmlNonBlocking()
-	xmlbody += api.xmlAccount(msisdn)
-	xmlbody += api.xmlAmount(amount)
-	xmlbody += api.xmlNarrative(narrative)
-	xmlbody += api.xmlExternalReference()
-	xmlbody += api.xmlInternalReference()
-	xmlbody += api.xmlProviderReferenceText()
-	xmlbody = api.createXml("acwithdrawfunds", xmlbody)
+	if api.AutoExternalReference && len(req.ExternalReference) == 0 {
+		req.ExternalReference = newExternalReference()
+	}
 
 	var response DepositResponse
-	resp, err := api.GetXmlResponse(xmlbody)
+	req.Account = normalizeMSISDN(req.Account)
+	var r Resp
+	resp, err := api.query(ctx, &req)
+	if err == nil {
+		err = xml.Unmarshal(resp, &r)
+	}
 	if err != nil {
-		return response, err
+		return api.resolveOutcome(ctx, req.ExternalReference, err)
 	}
-	var r Resp
-	err = xml.Unmarshal(resp, &r)
 	response = r.Response
-	return response, err
+	return response, response.yoError()
 }
 
 /*
@@ -764,65 +938,10 @@ func (api *YoAPI) ReceivePaymentFailureNotification(failed_transaction_reference
 
 func (api *YoAPI) verifyPaymentNotification(date_time, amount, narrative, network_ref, external_ref, msisdn, signature string) (bool, error) {
 	data := date_time + amount + narrative + network_ref + external_ref + msisdn
-	sign, err := base64.StdEncoding.DecodeString(signature)
-	if err != nil {
-		return false, err
-	}
-
-	block, _ := pem.Decode([]byte(pub_crt))
-	if err != nil {
-		return false, err
-	}
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return false, err
-	}
-
-	pubkey := cert.PublicKey.(*rsa.PublicKey)
-
-	hash := sha1.New()
-	hash.Write([]byte(data))
-	digest := hash.Sum(nil)
-	err = rsa.VerifyPKCS1v15(pubkey, crypto.SHA1, digest, sign)
-	if err != nil {
-		return false, err
-	}
-	return true, nil
-	/*
-	   $key_id = openssl_pkey_get_public($key);
-	   $verified = openssl_verify($data, $signature, $key_id);
-	   openssl_free_key($key_id);
-	   if($verified == 1){
-	       return true;
-	   }
-	   return false;
-	*/
+	return api.verifySignature(data, signature)
 }
 
 func (api *YoAPI) verifyPaymentFailureNotification(failed_transaction_reference, transaction_init_date, verification string) (bool, error) {
 	data := failed_transaction_reference + transaction_init_date
-	sign, err := base64.StdEncoding.DecodeString(verification)
-	if err != nil {
-		return false, err
-	}
-
-	block, _ := pem.Decode([]byte(pub_crt))
-	if err != nil {
-		return false, err
-	}
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return false, err
-	}
-
-	pubkey := cert.PublicKey.(*rsa.PublicKey)
-
-	hash := sha1.New()
-	hash.Write([]byte(data))
-	digest := hash.Sum(nil)
-	err = rsa.VerifyPKCS1v15(pubkey, crypto.SHA1, digest, sign)
-	if err != nil {
-		return false, err
-	}
-	return true, nil
+	return api.verifySignature(data, verification)
 }
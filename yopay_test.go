@@ -1,14 +1,34 @@
-package yopay
+package yopay_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
+
+	"github.com/voyager3m/yopay"
+	"github.com/voyager3m/yopay/yopaytest"
 )
 
-func newTestingApi(t *testing.T) *YoAPI {
-	result := NewYoApi("90003851865", "1168170290")
-	result.YoUrl = "https://sandbox.yo.co.ug/services/yopaymentsdev/task.php" //< sandbox url
+var gateway *yopaytest.Gateway
+
+func TestMain(m *testing.M) {
+	gateway = yopaytest.NewGateway()
+	gateway.Username = "user"
+	gateway.Password = "password"
+	gateway.SetBalance(yopaytest.MobileMoneyCurrency, 100000)
+	code := m.Run()
+	gateway.Close()
+	os.Exit(code)
+}
+
+func newTestingApi(t *testing.T) *yopay.YoAPI {
+	result := yopay.NewYoApi("user", "password")
+	result.YoUrl = gateway.URL //< in-process fake of the sandbox
 	return &result
 }
 
@@ -101,3 +121,20 @@ func TestWithdrawFunds(t *testing.T) {
 	}
 	t.Log(r.Status)
 }
+
+func TestGetAcctBalanceContextCanceled(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+	yo := newTestingApi(t)
+	yo.YoUrl = srv.URL
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := yo.GetAcctBalanceContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+// yopaytest project callback.go
+package yopaytest
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/voyager3m/yopay"
+)
+
+// notify posts the notification for the new state of t, called with g.mu held
+func (g *Gateway) notify(t *Transaction) {
+	if g.Signer == nil || g.closed {
+		return
+	}
+	var target string
+	var form url.Values
+	switch {
+	case t.State == yopay.StateSucceeded && len(t.instantNotificationUrl) > 0:
+		target = t.instantNotificationUrl
+		form = g.Signer.PaymentForm(yopay.PaymentNotificationResponse{
+			DateTime:    t.Completed.In(eastAfricaTime).Format(timeLayout),
+			Amount:      formatAmount(abs(t.Amount)),
+			Narrative:   t.Narrative,
+			NetworkRef:  t.TransactionReference,
+			ExternalRef: t.ExternalReference,
+			Msisdn:      t.Account,
+		})
+	case t.State == yopay.StateFailed && len(t.failureNotificationUrl) > 0:
+		target = t.failureNotificationUrl
+		form = g.Signer.FailureForm(yopay.PaymentFailureNotificationResponse{
+			FailedTransactionReference: t.TransactionReference,
+			TransactionInitDate:        t.Initiated.In(eastAfricaTime).Format(timeLayout),
+		})
+	default:
+		return
+	}
+
+	g.callbacks.Add(1)
+	go func() {
+		defer g.callbacks.Done()
+		resp, err := http.PostForm(target, form)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = &CallbackError{Url: target, StatusCode: resp.StatusCode}
+			}
+		}
+		if err != nil && g.OnCallbackError != nil {
+			g.OnCallbackError(err)
+		}
+	}()
+}
+
+/* CallbackError
+Notification url did not acknowledge a notification
+*/
+type CallbackError struct {
+	Url        string
+	StatusCode int
+}
+
+func (e *CallbackError) Error() string {
+	return "notification to " + e.Url + " failed: " + http.StatusText(e.StatusCode)
+}
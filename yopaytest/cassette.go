@@ -0,0 +1,228 @@
+// yopaytest project cassette.go
+package yopaytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sync"
+)
+
+/* Interaction
+One recorded gateway exchange, Fields holds the request elements used for matching
+*/
+type Interaction struct {
+	Method     string
+	Fields     map[string]string
+	Request    string
+	StatusCode int
+	Response   string
+}
+
+/* Cassette
+Recorded exchanges in the order they happened
+*/
+type Cassette struct {
+	Interactions []Interaction
+}
+
+// RecorderMode selects whether a Recorder records or replays
+type RecorderMode int
+
+const (
+	// forward requests to the gateway and record the exchanges
+	ModeRecord RecorderMode = iota
+	// answer requests from the cassette, the gateway is never contacted
+	ModeReplay
+)
+
+/* Recorder
+http.RoundTripper recording gateway exchanges to a cassette file and replaying them,
+use it as the Transport of YoAPI.HTTPClient.
+APIUsername and APIPassword are scrubbed from the recorded requests.
+Requests are matched by the Method element and the other request elements except credentials,
+so generated references (YoAPI.AutoExternalReference) do not replay.
+Identical requests replay the recorded answers in order, the last one repeats once they are used up.
+*/
+type Recorder struct {
+	Path string
+	Mode RecorderMode
+	// transport of the recorded requests, nil for http.DefaultTransport
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+/* NewRecorder
+Create recorder for the cassette at path, ModeReplay loads it
+*/
+func NewRecorder(path string, mode RecorderMode) (*Recorder, error) {
+	r := &Recorder{Path: path, Mode: mode}
+	if mode == ModeReplay {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("yopaytest: cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+/* Client
+http client using the recorder, for YoAPI.HTTPClient
+*/
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+/* Save
+Write the recorded exchanges to Path
+*/
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(&r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Path, append(data, '\n'), 0644)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	method, fields, err := matchFields(body)
+	if err != nil {
+		return nil, err
+	}
+	if r.Mode == ModeReplay {
+		return r.replay(req, method, fields)
+	}
+
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	out := req.Clone(req.Context())
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Method:     method,
+		Fields:     fields,
+		Request:    string(scrubCredentials(body)),
+		StatusCode: resp.StatusCode,
+		Response:   string(respBody),
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, method string, fields map[string]string) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for i, in := range r.cassette.Interactions {
+		if in.Method != method || !sameFields(in.Fields, fields) {
+			continue
+		}
+		last = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("yopaytest: no recorded exchange for %s %v", method, fields)
+	}
+	r.used[last] = true
+	in := r.cassette.Interactions[last]
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.StatusCode, http.StatusText(in.StatusCode)),
+		StatusCode:    in.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/xml; charset=utf-8"}},
+		Body:          ioutil.NopCloser(bytes.NewReader([]byte(in.Response))),
+		ContentLength: int64(len(in.Response)),
+		Request:       req,
+	}, nil
+}
+
+// matchFields extracts the Method element and the non-empty request elements except credentials
+func matchFields(body []byte) (string, map[string]string, error) {
+	var req struct {
+		Request struct {
+			Elements []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		}
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		return "", nil, fmt.Errorf("yopaytest: not a gateway request: %w", err)
+	}
+	var method string
+	fields := make(map[string]string)
+	for _, e := range req.Request.Elements {
+		switch e.XMLName.Local {
+		case "APIUsername", "APIPassword":
+		case "Method":
+			method = e.Value
+		default:
+			if len(e.Value) > 0 {
+				fields[e.XMLName.Local] = e.Value
+			}
+		}
+	}
+	if len(method) == 0 {
+		return "", nil, errors.New("yopaytest: request without Method")
+	}
+	return method, fields, nil
+}
+
+func sameFields(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+var credentialsPattern = regexp.MustCompile(`(<APIUsername>|<APIPassword>)[^<]*`)
+
+func scrubCredentials(body []byte) []byte {
+	return credentialsPattern.ReplaceAll(body, []byte("${1}***"))
+}
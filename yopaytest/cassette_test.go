@@ -0,0 +1,67 @@
+package yopaytest
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/voyager3m/yopay"
+)
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	api, g := newGatewayApi(t)
+	g.SetBalance(MobileMoneyCurrency, 1000)
+
+	rec, err := NewRecorder(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.HTTPClient = rec.Client()
+	w, err := api.WithdrawFunds("256771234567", 100, "withdraw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.GetAcctBalance(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.WithdrawFunds("256771234567", 100, "withdraw"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := api.GetAcctBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	g.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), api.Password) || strings.Contains(string(data), api.Username) {
+		t.Fatalf("credentials recorded:\n%s", data)
+	}
+
+	rep, err := NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayApi := yopay.NewYoApi("other", "credentials")
+	replayApi.YoUrl = g.URL
+	replayApi.HTTPClient = rep.Client()
+	if r, err := replayApi.WithdrawFunds("256771234567", 100, "withdraw"); err != nil || r.TransactionReference != w.TransactionReference {
+		t.Fatalf("replayed withdrawal: %+v %v", r, err)
+	}
+	replayApi.GetAcctBalance()
+	replayApi.WithdrawFunds("256771234567", 100, "withdraw")
+	if r, err := replayApi.GetAcctBalance(); err != nil || r.Balance.Currency[0].Balance != b.Balance.Currency[0].Balance {
+		t.Fatalf("replayed balance: %+v %v", r, err)
+	}
+	if _, err := replayApi.WithdrawFunds("256771234567", 200, "withdraw"); err == nil {
+		t.Fatal("unrecorded request replayed")
+	}
+}
@@ -0,0 +1,7 @@
+// yopaytest project doc.go
+
+/*
+yopaytest document
+Helpers for testing code built on yopay without the Yo! Payments gateway
+*/
+package yopaytest
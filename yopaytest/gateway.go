@@ -0,0 +1,565 @@
+// yopaytest project gateway.go
+package yopaytest
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/voyager3m/yopay"
+)
+
+const (
+	// currency of deposits and withdrawals
+	MobileMoneyCurrency = string(yopay.UGXMTNMobileMoney)
+	// currency debited by acsendairtimemobile
+	AirtimeCurrency = string(yopay.UGXMTNAirtime)
+)
+
+// date format of the gateway, in East Africa Time
+const timeLayout = "2006-01-02 15:04:05"
+
+var eastAfricaTime = time.FixedZone("EAT", 3*60*60)
+
+/* Transaction
+Ledger entry of the fake gateway, amounts are in hundredths of the currency unit
+*/
+type Transaction struct {
+	SystemId             string
+	TransactionReference string
+	ExternalReference    string
+	Method               string
+	Account              string
+	CurrencyCode         string
+	Amount               int64
+	// balance of CurrencyCode after the transaction
+	Balance   int64
+	Narrative string
+	State     yopay.TransactionState
+	Initiated time.Time
+	Completed time.Time
+
+	instantNotificationUrl string
+	failureNotificationUrl string
+}
+
+/* Gateway
+In-process fake of the Yo! Payments gateway with an in-memory ledger, safe for concurrent use.
+Point YoAPI.YoUrl to URL
+*/
+type Gateway struct {
+	*httptest.Server
+
+	/* Username, Password
+	   Credentials accepted by the gateway, empty accepts any
+	*/
+	Username string
+	Password string
+
+	/* Signer
+	   Signs the notifications posted to InstantNotificationUrl when a deposit succeeds
+	   and to FailureNotificationUrl when it fails
+	   Default: nil, no notifications
+	*/
+	Signer *Signer
+
+	// called when a notification could not be delivered
+	OnCallbackError func(err error)
+
+	/* Now
+	   Clock of the ledger, set it to backdate transactions
+	   Default: nil, time.Now
+	*/
+	Now func() time.Time
+
+	mu           sync.Mutex
+	balances     map[string]int64
+	transactions []*Transaction
+	invalid      map[string]bool
+	nextId       int
+	scenarios    []*scenarioRule
+	timers       []*time.Timer
+	callbacks    sync.WaitGroup
+	closed       bool
+}
+
+/* NewGateway
+Start fake gateway, Close it when done
+*/
+func NewGateway() *Gateway {
+	g := &Gateway{
+		balances: make(map[string]int64),
+		invalid:  make(map[string]bool),
+	}
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serve))
+	return g
+}
+
+/* Close
+Stop pending state transitions, wait for the notifications in flight and shut down the server
+*/
+func (g *Gateway) Close() {
+	g.mu.Lock()
+	g.closed = true
+	for _, timer := range g.timers {
+		timer.Stop()
+	}
+	g.timers = nil
+	g.mu.Unlock()
+	g.callbacks.Wait()
+	g.Server.Close()
+}
+
+/* SetBalance
+Set account balance of currency_code in whole units
+*/
+func (g *Gateway) SetBalance(currency_code string, amount int64) {
+	g.mu.Lock()
+	g.balances[currency_code] = amount * 100
+	g.mu.Unlock()
+}
+
+/* Balance
+Account balance of currency_code in whole units
+*/
+func (g *Gateway) Balance(currency_code string) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.balances[currency_code] / 100
+}
+
+/* SetAccountValid
+Set the result of acverifyaccountvalidity for msisdn, all accounts are valid by default
+*/
+func (g *Gateway) SetAccountValid(msisdn string, valid bool) {
+	g.mu.Lock()
+	g.invalid[msisdn] = !valid
+	g.mu.Unlock()
+}
+
+/* Transactions
+Copy of the ledger, oldest first
+*/
+func (g *Gateway) Transactions() []Transaction {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	result := make([]Transaction, len(g.transactions))
+	for i, t := range g.transactions {
+		result[i] = *t
+	}
+	return result
+}
+
+type gatewayRequest struct {
+	XMLName xml.Name `xml:"AutoCreate"`
+	Request gatewayParams
+}
+
+// all fields of all methods
+type gatewayParams struct {
+	APIUsername                 string
+	APIPassword                 string
+	Method                      string
+	NonBlocking                 string
+	Account                     string
+	Amount                      string
+	Narrative                   string
+	ExternalReference           string
+	InternalReference           string
+	ProviderReferenceText       string
+	InstantNotificationUrl      string
+	FailureNotificationUrl      string
+	CurrencyCode                string
+	BeneficiaryAccount          string
+	BeneficiaryEmail            string
+	DepositTransactionType      string
+	TransactionReference        string
+	PrivateTransactionReference string
+	TransactionEntryDesignation string
+	StartDate                   string
+	EndDate                     string
+	TransactionStatus           string
+	ResultSetLimit              string
+}
+
+type gatewayResponse struct {
+	XMLName  xml.Name `xml:"AutoCreate"`
+	Response interface{}
+}
+
+type statusResponse struct {
+	Status               string
+	StatusCode           string
+	StatusMessage        string `xml:",omitempty"`
+	ErrorMessageCode     string `xml:",omitempty"`
+	ErrorMessage         string `xml:",omitempty"`
+	TransactionStatus    string `xml:",omitempty"`
+	TransactionReference string `xml:",omitempty"`
+	// actransactioncheckstatus
+	Amount                    string `xml:",omitempty"`
+	AmountFormatted           string `xml:",omitempty"`
+	CurrencyCode              string `xml:",omitempty"`
+	TransactionInitiationDate string `xml:",omitempty"`
+	TransactionCompletionDate string `xml:",omitempty"`
+	// acverifyaccountvalidity
+	Valid string `xml:",omitempty"`
+}
+
+type balanceResponse struct {
+	Status     string
+	StatusCode string
+	Currency   []balanceCurrency `xml:"Balance>Currency"`
+}
+
+type balanceCurrency struct {
+	Code    string
+	Balance string
+}
+
+type statementResponse struct {
+	Status               string
+	StatusCode           string
+	TotalTransactions    int
+	ReturnedTransactions int
+	Transaction          []statementTransaction `xml:"Transactions>Transaction"`
+}
+
+type statementTransaction struct {
+	TransactionSystemId                string
+	TransactionReference               string
+	TransactionStatus                  string
+	InitiationDate                     string
+	CompletionDate                     string
+	NarrativeBase64                    string
+	Currency                           string
+	Amount                             string
+	Balance                            string
+	GeneralType                        string
+	DetailedType                       string
+	BeneficiaryMsisdn                  string
+	BeneficiaryBase64                  string
+	SenderMsisdn                       string
+	SenderBase64                       string
+	Base64TransactionExternalReference string
+	TransactionEntryDesignation        string
+}
+
+func (g *Gateway) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req gatewayRequest
+	if err := xml.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response, scenario := g.handle(&req)
+	out, err := xml.Marshal(gatewayResponse{Response: response})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out = append([]byte(xml.Header), out...)
+	if scenario != nil {
+		if !scenario.respond(w, r) {
+			return
+		}
+		if scenario.Truncate {
+			out = out[:len(out)/2]
+		}
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write(out)
+}
+
+func (g *Gateway) handle(req *gatewayRequest) (interface{}, *Scenario) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r := &req.Request
+	if (len(g.Username) > 0 && r.APIUsername != g.Username) || (len(g.Password) > 0 && r.APIPassword != g.Password) {
+		return errorResponse(yopay.ErrAuthFailed, "Invalid API credentials"), nil
+	}
+	scenario := g.match(r)
+	if scenario != nil && scenario.Err != nil {
+		return errorResponse(scenario.Err, scenario.Err.Error()), scenario
+	}
+	return g.dispatch(r, scenario), scenario
+}
+
+func (g *Gateway) dispatch(r *gatewayParams, scenario *Scenario) interface{} {
+	var states []Transition
+	if scenario != nil {
+		states = scenario.States
+	}
+	switch r.Method {
+	case "acdepositfunds":
+		return g.transact(r, MobileMoneyCurrency, 1, states)
+	case "acwithdrawfunds":
+		return g.transact(r, MobileMoneyCurrency, -1, states)
+	case "acinternaltransfer", "acsendairtimeinternal":
+		return g.transact(r, r.CurrencyCode, -1, states)
+	case "acsendairtimemobile":
+		return g.transact(r, AirtimeCurrency, -1, states)
+	case "actransactioncheckstatus":
+		return g.checkStatus(r.TransactionReference, r.PrivateTransactionReference)
+	case "acacctbalance":
+		return g.balance()
+	case "acgetministatement":
+		return g.ministatement(r)
+	case "acverifyaccountvalidity":
+		valid := "TRUE"
+		if g.invalid[r.Account] {
+			valid = "FALSE"
+		}
+		return &statusResponse{Status: "OK", StatusCode: "0", Valid: valid}
+	}
+	return &statusResponse{Status: "ERROR", StatusCode: "-1", StatusMessage: "Unknown method " + r.Method}
+}
+
+// transact records a transaction moving amount in or out (sign) of the currency_code balance
+func (g *Gateway) transact(r *gatewayParams, currency_code string, sign int64, states []Transition) interface{} {
+	money, err := yopay.ParseMoney(r.Amount, yopay.CurrencyCode(currency_code))
+	amount := money.Minor
+	if err != nil || amount <= 0 {
+		return &statusResponse{Status: "ERROR", StatusCode: "-1", StatusMessage: "Invalid amount " + r.Amount}
+	}
+	if len(r.ExternalReference) > 0 && g.find("", r.ExternalReference) != nil {
+		return errorResponse(yopay.ErrDuplicateReference, "Duplicate external reference "+r.ExternalReference)
+	}
+	account := r.Account
+	if len(account) == 0 {
+		account = r.BeneficiaryAccount
+	}
+	if g.invalid[account] {
+		return errorResponse(yopay.ErrInvalidAccount, "Invalid account "+account)
+	}
+	if sign < 0 && g.balances[currency_code] < amount {
+		return errorResponse(yopay.ErrInsufficientBalance, "Insufficient balance")
+	}
+
+	g.nextId++
+	t := &Transaction{
+		SystemId:               strconv.Itoa(g.nextId),
+		TransactionReference:   fmt.Sprintf("yopaytest-%d", g.nextId),
+		ExternalReference:      r.ExternalReference,
+		Method:                 r.Method,
+		Account:                account,
+		CurrencyCode:           currency_code,
+		Amount:                 sign * amount,
+		Balance:                g.balances[currency_code],
+		Narrative:              r.Narrative,
+		State:                  yopay.StatePending,
+		Initiated:              g.now(),
+		instantNotificationUrl: r.InstantNotificationUrl,
+		failureNotificationUrl: r.FailureNotificationUrl,
+	}
+	g.transactions = append(g.transactions, t)
+	if len(states) == 0 {
+		states = []Transition{{State: yopay.StateSucceeded}}
+	}
+	for _, tr := range states {
+		if tr.After <= 0 {
+			g.setState(t, tr.State)
+			continue
+		}
+		state := tr.State
+		g.timers = append(g.timers, time.AfterFunc(tr.After, func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if !g.closed {
+				g.setState(t, state)
+			}
+		}))
+	}
+
+	if strings.EqualFold(r.NonBlocking, "TRUE") || t.State == yopay.StatePending {
+		return &statusResponse{Status: "OK", StatusCode: "1", TransactionStatus: string(yopay.StatePending), TransactionReference: t.TransactionReference}
+	}
+	return &statusResponse{Status: "OK", StatusCode: "0", TransactionStatus: string(t.State), TransactionReference: t.TransactionReference}
+}
+
+// setState moves t to state, terminal states are final, called with g.mu held
+func (g *Gateway) setState(t *Transaction, state yopay.TransactionState) {
+	if t.State.IsTerminal() {
+		return
+	}
+	if state == yopay.StateSucceeded {
+		if g.balances[t.CurrencyCode]+t.Amount < 0 {
+			state = yopay.StateFailed
+		} else {
+			g.balances[t.CurrencyCode] += t.Amount
+			t.Balance = g.balances[t.CurrencyCode]
+		}
+	}
+	t.State = state
+	if state.IsTerminal() {
+		t.Completed = g.now()
+		g.notify(t)
+	}
+}
+
+func (g *Gateway) now() time.Time {
+	if g.Now != nil {
+		return g.Now()
+	}
+	return time.Now()
+}
+
+func (g *Gateway) find(transaction_reference, external_reference string) *Transaction {
+	for _, t := range g.transactions {
+		if (len(transaction_reference) > 0 && t.TransactionReference == transaction_reference) ||
+			(len(external_reference) > 0 && t.ExternalReference == external_reference) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (g *Gateway) checkStatus(transaction_reference, private_transaction_reference string) interface{} {
+	t := g.find(transaction_reference, private_transaction_reference)
+	if t == nil {
+		return &statusResponse{Status: "ERROR", StatusCode: "-1", StatusMessage: "Transaction not found"}
+	}
+	amount := formatAmount(abs(t.Amount))
+	response := &statusResponse{
+		Status:                    "OK",
+		StatusCode:                "0",
+		TransactionStatus:         string(t.State),
+		TransactionReference:      t.TransactionReference,
+		Amount:                    amount,
+		AmountFormatted:           amount,
+		CurrencyCode:              t.CurrencyCode,
+		TransactionInitiationDate: t.Initiated.In(eastAfricaTime).Format(timeLayout),
+	}
+	if t.State.IsTerminal() {
+		response.TransactionCompletionDate = t.Completed.In(eastAfricaTime).Format(timeLayout)
+	}
+	return response
+}
+
+func (g *Gateway) balance() interface{} {
+	response := &balanceResponse{Status: "OK", StatusCode: "0"}
+	codes := make([]string, 0, len(g.balances))
+	for code := range g.balances {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		response.Currency = append(response.Currency, balanceCurrency{code, formatAmount(g.balances[code])})
+	}
+	return response
+}
+
+func (g *Gateway) ministatement(r *gatewayParams) interface{} {
+	var start, end time.Time
+	var err error
+	if len(r.StartDate) > 0 {
+		if start, err = time.ParseInLocation(timeLayout, r.StartDate, eastAfricaTime); err != nil {
+			return &statusResponse{Status: "ERROR", StatusCode: "-1", StatusMessage: "Invalid StartDate"}
+		}
+	}
+	if len(r.EndDate) > 0 {
+		if end, err = time.ParseInLocation(timeLayout, r.EndDate, eastAfricaTime); err != nil {
+			return &statusResponse{Status: "ERROR", StatusCode: "-1", StatusMessage: "Invalid EndDate"}
+		}
+	}
+	limit := 15
+	if len(r.ResultSetLimit) > 0 {
+		if limit, err = strconv.Atoi(r.ResultSetLimit); err != nil || limit < 0 {
+			return &statusResponse{Status: "ERROR", StatusCode: "-1", StatusMessage: "Invalid ResultSetLimit"}
+		}
+	}
+	states := make(map[string]bool)
+	for _, s := range strings.Split(r.TransactionStatus, ",") {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			states[s] = true
+		}
+	}
+
+	response := &statementResponse{Status: "OK", StatusCode: "0"}
+	for i := len(g.transactions) - 1; i >= 0; i-- {
+		t := g.transactions[i]
+		// the gateway reports times with second precision
+		initiated := t.Initiated.Truncate(time.Second)
+		if (!start.IsZero() && initiated.Before(start)) || (!end.IsZero() && initiated.After(end)) ||
+			(len(states) > 0 && !states[string(t.State)]) ||
+			(len(r.CurrencyCode) > 0 && r.CurrencyCode != t.CurrencyCode) ||
+			(len(r.ExternalReference) > 0 && r.ExternalReference != t.ExternalReference) ||
+			r.TransactionEntryDesignation == "CHARGES" {
+			continue
+		}
+		response.TotalTransactions++
+		if limit > 0 && len(response.Transaction) >= limit {
+			continue
+		}
+		entry := statementTransaction{
+			TransactionSystemId:                t.SystemId,
+			TransactionReference:               t.TransactionReference,
+			TransactionStatus:                  string(t.State),
+			InitiationDate:                     t.Initiated.In(eastAfricaTime).Format(timeLayout),
+			NarrativeBase64:                    base64.StdEncoding.EncodeToString([]byte(t.Narrative)),
+			Currency:                           t.CurrencyCode,
+			Amount:                             formatAmount(t.Amount),
+			Balance:                            formatAmount(t.Balance),
+			GeneralType:                        t.Method,
+			DetailedType:                       t.Method,
+			Base64TransactionExternalReference: base64.StdEncoding.EncodeToString([]byte(t.ExternalReference)),
+			TransactionEntryDesignation:        "TRANSACTION",
+		}
+		if t.State.IsTerminal() {
+			entry.CompletionDate = t.Completed.In(eastAfricaTime).Format(timeLayout)
+		}
+		if t.Amount < 0 {
+			entry.BeneficiaryMsisdn = t.Account
+		} else {
+			entry.SenderMsisdn = t.Account
+		}
+		response.Transaction = append(response.Transaction, entry)
+	}
+	response.ReturnedTransactions = len(response.Transaction)
+	return response
+}
+
+// sentinelCodes is the StatusCode the fake reports for each sentinel,
+// it follows the status codes the client matches in errors.go
+var sentinelCodes = map[error]string{
+	yopay.ErrAuthFailed:          "-3",
+	yopay.ErrInvalidAccount:      "-18",
+	yopay.ErrInsufficientBalance: "-22",
+	yopay.ErrDuplicateReference:  "-33",
+}
+
+// errorResponse reports the status code the client maps to sentinel, -1 for other errors
+func errorResponse(sentinel error, message string) *statusResponse {
+	code, ok := sentinelCodes[sentinel]
+	if !ok {
+		code = "-1"
+	}
+	return &statusResponse{Status: "ERROR", StatusCode: code, StatusMessage: message, ErrorMessage: message}
+}
+
+// formatAmount formats hundredths as a decimal amount
+func formatAmount(n int64) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, n/100, n%100)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
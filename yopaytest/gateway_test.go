@@ -0,0 +1,93 @@
+package yopaytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/voyager3m/yopay"
+)
+
+func newGatewayApi(t *testing.T) (*yopay.YoAPI, *Gateway) {
+	g := NewGateway()
+	t.Cleanup(g.Close)
+	api := yopay.NewYoApi("user", "password")
+	api.YoUrl = g.URL
+	return &api, g
+}
+
+func TestGatewayLedger(t *testing.T) {
+	api, g := newGatewayApi(t)
+	ctx := context.Background()
+	g.SetBalance("UGX-MTNAT", 500)
+
+	d, err := api.DepositFundsContext(ctx, yopay.DepositFundsRequest{Account: "256771234567", Amount: yopay.Money{Minor: 200000}, Narrative: "deposit", ExternalReference: "inv-1"})
+	if err != nil || d.TransactionStatus != yopay.StateSucceeded {
+		t.Fatalf("deposit: %+v %v", d, err)
+	}
+	_, err = api.DepositFundsContext(ctx, yopay.DepositFundsRequest{Account: "256771234567", Amount: yopay.Money{Minor: 200000}, Narrative: "deposit", ExternalReference: "inv-1"})
+	if !errors.Is(err, yopay.ErrDuplicateReference) {
+		t.Fatalf("duplicate reference accepted: %v", err)
+	}
+	if _, err := api.WithdrawFunds("256771234567", 5000, "too much"); !errors.Is(err, yopay.ErrInsufficientBalance) {
+		t.Fatalf("overdraft accepted: %v", err)
+	}
+	if _, err := api.WithdrawFunds("256771234567", 500, "withdraw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.SendAirtimeMobile("256771234567", 100, "airtime"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.InternalTransfer(MobileMoneyCurrency, 500, "100200", "someone@example.com", "transfer"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Balance(MobileMoneyCurrency) != 1000 || g.Balance("UGX-MTNAT") != 400 {
+		t.Fatalf("unexpected balances %d %d", g.Balance(MobileMoneyCurrency), g.Balance("UGX-MTNAT"))
+	}
+
+	b, err := api.GetAcctBalance()
+	if err != nil || len(b.Balance.Currency) != 2 || b.Balance.Currency[1].Balance != "1000.00" {
+		t.Fatalf("balance: %+v %v", b, err)
+	}
+
+	s, err := api.CheckTransactionStatus("", "inv-1")
+	if err != nil || s.TransactionReference != d.TransactionReference || s.Amount != "2000.00" {
+		t.Fatalf("status: %+v %v", s, err)
+	}
+
+	now := time.Now().In(time.FixedZone("EAT", 3*60*60))
+	m, err := api.GetMinistatement(now.Add(-time.Hour).Format("2006-01-02 15:04:05"), now.Add(time.Hour).Format("2006-01-02 15:04:05"), "", MobileMoneyCurrency, "2", "", "")
+	if err != nil || m.TotalTransactions != "3" || m.ReturnedTransactions != "2" {
+		t.Fatalf("ministatement: %+v %v", m, err)
+	}
+
+	g.SetAccountValid("256770000000", false)
+	if valid, err := api.VerifyAccountValidity("256770000000"); valid || err != nil {
+		t.Fatalf("invalid account verified: %v", err)
+	}
+	if valid, err := api.VerifyAccountValidity("256771234567"); !valid || err != nil {
+		t.Fatalf("valid account rejected: %v", err)
+	}
+}
+
+func TestGatewayCredentials(t *testing.T) {
+	api, g := newGatewayApi(t)
+	g.Password = "secret"
+	if _, err := api.GetAcctBalance(); !errors.Is(err, yopay.ErrAuthFailed) {
+		t.Fatalf("wrong password accepted: %v", err)
+	}
+}
+
+func TestGatewaySentinelCodes(t *testing.T) {
+	for sentinel, code := range sentinelCodes {
+		r := errorResponse(sentinel, sentinel.Error())
+		err := &yopay.YoError{Status: r.Status, StatusCode: r.StatusCode}
+		if r.StatusCode != code || !errors.Is(err, sentinel) {
+			t.Errorf("status code %s does not match %v", r.StatusCode, sentinel)
+		}
+	}
+	if r := errorResponse(errors.New("other"), "other"); r.StatusCode != "-1" {
+		t.Errorf("unexpected status code %s for an unknown error", r.StatusCode)
+	}
+}
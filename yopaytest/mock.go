@@ -0,0 +1,143 @@
+// yopaytest project mock.go
+package yopaytest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/voyager3m/yopay"
+)
+
+/* MockCall
+Call received by MockClient, Args are the arguments after the context
+*/
+type MockCall struct {
+	Method string
+	Args   []interface{}
+}
+
+/* MockClient
+yopay.Client calling the function fields, calling a method whose function is nil panics.
+Safe for concurrent use.
+*/
+type MockClient struct {
+	DepositFundsContextFunc               func(ctx context.Context, req yopay.DepositFundsRequest) (yopay.DepositResponse, error)
+	WithdrawFundsContextFunc              func(ctx context.Context, req yopay.WithdrawFundsRequest) (yopay.DepositResponse, error)
+	CheckTransactionStatusContextFunc     func(ctx context.Context, req yopay.TransactionCheckStatusRequest) (yopay.TransactionStatus, error)
+	InternalTransferContextFunc           func(ctx context.Context, req yopay.InternalTransferRequest) (yopay.DepositResponse, error)
+	SendAirtimeMobileContextFunc          func(ctx context.Context, req yopay.SendAirtimeMobileRequest) (yopay.DepositResponse, error)
+	SendAirtimeInternalContextFunc        func(ctx context.Context, req yopay.SendAirtimeInternalRequest) (yopay.DepositResponse, error)
+	GetAcctBalanceContextFunc             func(ctx context.Context) (yopay.BalanceResponse, error)
+	GetMinistatementContextFunc           func(ctx context.Context, req yopay.MinistatementRequest) (yopay.MinistatementResponse, error)
+	VerifyAccountValidityContextFunc      func(ctx context.Context, msisdn string) (bool, error)
+	ReceivePaymentNotificationFunc        func(date_time, amount, narrative, network_ref, external_ref, msisdn, signature string) (yopay.PaymentNotificationResponse, error)
+	ReceivePaymentFailureNotificationFunc func(failed_transaction_reference, transaction_init_date, verification string) (yopay.PaymentFailureNotificationResponse, error)
+
+	mu    sync.Mutex
+	calls []MockCall
+}
+
+var _ yopay.Client = (*MockClient)(nil)
+
+/* Calls
+Calls received so far, oldest first
+*/
+func (m *MockClient) Calls() []MockCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MockCall(nil), m.calls...)
+}
+
+func (m *MockClient) record(method string, args ...interface{}) {
+	m.mu.Lock()
+	m.calls = append(m.calls, MockCall{Method: method, Args: args})
+	m.mu.Unlock()
+}
+
+func (m *MockClient) DepositFundsContext(ctx context.Context, req yopay.DepositFundsRequest) (yopay.DepositResponse, error) {
+	if m.DepositFundsContextFunc == nil {
+		panic("yopaytest: MockClient.DepositFundsContextFunc is nil")
+	}
+	m.record("DepositFundsContext", req)
+	return m.DepositFundsContextFunc(ctx, req)
+}
+
+func (m *MockClient) WithdrawFundsContext(ctx context.Context, req yopay.WithdrawFundsRequest) (yopay.DepositResponse, error) {
+	if m.WithdrawFundsContextFunc == nil {
+		panic("yopaytest: MockClient.WithdrawFundsContextFunc is nil")
+	}
+	m.record("WithdrawFundsContext", req)
+	return m.WithdrawFundsContextFunc(ctx, req)
+}
+
+func (m *MockClient) CheckTransactionStatusContext(ctx context.Context, req yopay.TransactionCheckStatusRequest) (yopay.TransactionStatus, error) {
+	if m.CheckTransactionStatusContextFunc == nil {
+		panic("yopaytest: MockClient.CheckTransactionStatusContextFunc is nil")
+	}
+	m.record("CheckTransactionStatusContext", req)
+	return m.CheckTransactionStatusContextFunc(ctx, req)
+}
+
+func (m *MockClient) InternalTransferContext(ctx context.Context, req yopay.InternalTransferRequest) (yopay.DepositResponse, error) {
+	if m.InternalTransferContextFunc == nil {
+		panic("yopaytest: MockClient.InternalTransferContextFunc is nil")
+	}
+	m.record("InternalTransferContext", req)
+	return m.InternalTransferContextFunc(ctx, req)
+}
+
+func (m *MockClient) SendAirtimeMobileContext(ctx context.Context, req yopay.SendAirtimeMobileRequest) (yopay.DepositResponse, error) {
+	if m.SendAirtimeMobileContextFunc == nil {
+		panic("yopaytest: MockClient.SendAirtimeMobileContextFunc is nil")
+	}
+	m.record("SendAirtimeMobileContext", req)
+	return m.SendAirtimeMobileContextFunc(ctx, req)
+}
+
+func (m *MockClient) SendAirtimeInternalContext(ctx context.Context, req yopay.SendAirtimeInternalRequest) (yopay.DepositResponse, error) {
+	if m.SendAirtimeInternalContextFunc == nil {
+		panic("yopaytest: MockClient.SendAirtimeInternalContextFunc is nil")
+	}
+	m.record("SendAirtimeInternalContext", req)
+	return m.SendAirtimeInternalContextFunc(ctx, req)
+}
+
+func (m *MockClient) GetAcctBalanceContext(ctx context.Context) (yopay.BalanceResponse, error) {
+	if m.GetAcctBalanceContextFunc == nil {
+		panic("yopaytest: MockClient.GetAcctBalanceContextFunc is nil")
+	}
+	m.record("GetAcctBalanceContext")
+	return m.GetAcctBalanceContextFunc(ctx)
+}
+
+func (m *MockClient) GetMinistatementContext(ctx context.Context, req yopay.MinistatementRequest) (yopay.MinistatementResponse, error) {
+	if m.GetMinistatementContextFunc == nil {
+		panic("yopaytest: MockClient.GetMinistatementContextFunc is nil")
+	}
+	m.record("GetMinistatementContext", req)
+	return m.GetMinistatementContextFunc(ctx, req)
+}
+
+func (m *MockClient) VerifyAccountValidityContext(ctx context.Context, msisdn string) (bool, error) {
+	if m.VerifyAccountValidityContextFunc == nil {
+		panic("yopaytest: MockClient.VerifyAccountValidityContextFunc is nil")
+	}
+	m.record("VerifyAccountValidityContext", msisdn)
+	return m.VerifyAccountValidityContextFunc(ctx, msisdn)
+}
+
+func (m *MockClient) ReceivePaymentNotification(date_time, amount, narrative, network_ref, external_ref, msisdn, signature string) (yopay.PaymentNotificationResponse, error) {
+	if m.ReceivePaymentNotificationFunc == nil {
+		panic("yopaytest: MockClient.ReceivePaymentNotificationFunc is nil")
+	}
+	m.record("ReceivePaymentNotification", date_time, amount, narrative, network_ref, external_ref, msisdn, signature)
+	return m.ReceivePaymentNotificationFunc(date_time, amount, narrative, network_ref, external_ref, msisdn, signature)
+}
+
+func (m *MockClient) ReceivePaymentFailureNotification(failed_transaction_reference, transaction_init_date, verification string) (yopay.PaymentFailureNotificationResponse, error) {
+	if m.ReceivePaymentFailureNotificationFunc == nil {
+		panic("yopaytest: MockClient.ReceivePaymentFailureNotificationFunc is nil")
+	}
+	m.record("ReceivePaymentFailureNotification", failed_transaction_reference, transaction_init_date, verification)
+	return m.ReceivePaymentFailureNotificationFunc(failed_transaction_reference, transaction_init_date, verification)
+}
@@ -0,0 +1,37 @@
+package yopaytest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/voyager3m/yopay"
+)
+
+// payout depends on the role interface only
+func payout(ctx context.Context, w yopay.Withdrawer, msisdn string) (string, error) {
+	r, err := w.WithdrawFundsContext(ctx, yopay.WithdrawFundsRequest{Account: msisdn, Amount: yopay.Money{Minor: 10000}, Narrative: "payout"})
+	return r.TransactionReference, err
+}
+
+func TestMockClient(t *testing.T) {
+	m := &MockClient{
+		WithdrawFundsContextFunc: func(ctx context.Context, req yopay.WithdrawFundsRequest) (yopay.DepositResponse, error) {
+			return yopay.DepositResponse{Status: "OK", TransactionReference: "ref-" + req.Account}, nil
+		},
+	}
+	ref, err := payout(context.Background(), m, "256771234567")
+	if err != nil || ref != "ref-256771234567" {
+		t.Fatalf("payout: %s %v", ref, err)
+	}
+	calls := m.Calls()
+	if len(calls) != 1 || calls[0].Method != "WithdrawFundsContext" || calls[0].Args[0].(yopay.WithdrawFundsRequest).Amount.Minor != 10000 {
+		t.Fatalf("calls: %+v", calls)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("unset function did not panic")
+		}
+	}()
+	m.GetAcctBalanceContext(context.Background())
+}
@@ -0,0 +1,127 @@
+// yopaytest project scenario.go
+package yopaytest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/voyager3m/yopay"
+)
+
+/* Transition
+State a transaction enters After its initiation
+*/
+type Transition struct {
+	After time.Duration
+	State yopay.TransactionState
+}
+
+/* Scenario
+Rule changing how the gateway answers the requests it matches.
+The request is processed before the response is delayed, dropped or replaced,
+so a transaction is recorded even if the client never sees the answer.
+*/
+type Scenario struct {
+	/* Method, Account, ExternalReference
+	   The requests matched, empty fields match anything.
+	   Account matches Account or BeneficiaryAccount (the MSISDN),
+	   ExternalReference matches ExternalReference or PrivateTransactionReference
+	*/
+	Method            string
+	Account           string
+	ExternalReference string
+
+	/* Times
+	   Number of requests matched before the scenario is used up
+	   Default: 0, unlimited
+	*/
+	Times int
+
+	/* Err
+	   Reject the request with the status code of this yopay sentinel, e.g. yopay.ErrInsufficientBalance,
+	   other errors are reported with status code -1. Nothing is recorded.
+	*/
+	Err error
+
+	/* States
+	   A transaction created by the request starts PENDING and goes through States as time passes,
+	   the balance moves when it reaches SUCCEEDED. Final states other than SUCCEEDED and FAILED,
+	   e.g. INDETERMINATE, stay until the gateway is closed.
+	   Default: nil, the transaction succeeds at once
+	*/
+	States []Transition
+
+	// wait before responding, cut short when the client goes away
+	Delay time.Duration
+	// close the connection without a response
+	Drop bool
+	// respond with this status code and no XML, e.g. http.StatusInternalServerError
+	HTTPStatus int
+	// respond with the first half of the XML document
+	Truncate bool
+}
+
+type scenarioRule struct {
+	Scenario
+	used int
+}
+
+/* AddScenario
+Add a rule, the rules are tried in the order they were added and the first match wins
+*/
+func (g *Gateway) AddScenario(s Scenario) {
+	g.mu.Lock()
+	g.scenarios = append(g.scenarios, &scenarioRule{Scenario: s})
+	g.mu.Unlock()
+}
+
+/* ClearScenarios
+Remove all rules
+*/
+func (g *Gateway) ClearScenarios() {
+	g.mu.Lock()
+	g.scenarios = nil
+	g.mu.Unlock()
+}
+
+// match finds the rule for r and uses it up, called with g.mu held
+func (g *Gateway) match(r *gatewayParams) *Scenario {
+	for _, rule := range g.scenarios {
+		if (len(rule.Method) > 0 && rule.Method != r.Method) ||
+			(len(rule.Account) > 0 && rule.Account != r.Account && rule.Account != r.BeneficiaryAccount) ||
+			(len(rule.ExternalReference) > 0 && rule.ExternalReference != r.ExternalReference && rule.ExternalReference != r.PrivateTransactionReference) ||
+			(rule.Times > 0 && rule.used >= rule.Times) {
+			continue
+		}
+		rule.used++
+		return &rule.Scenario
+	}
+	return nil
+}
+
+// respond applies the response part of s, reports false if the response was already handled
+func (s *Scenario) respond(w http.ResponseWriter, r *http.Request) bool {
+	if s.Delay > 0 {
+		timer := time.NewTimer(s.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	if s.Drop {
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return false
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+	if s.HTTPStatus != 0 {
+		http.Error(w, http.StatusText(s.HTTPStatus), s.HTTPStatus)
+		return false
+	}
+	return true
+}
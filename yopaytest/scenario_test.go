@@ -0,0 +1,102 @@
+package yopaytest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/voyager3m/yopay"
+)
+
+func TestScenarioTransitions(t *testing.T) {
+	api, g := newGatewayApi(t)
+	signer, err := NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Signer = signer
+	signer.Trust(api)
+	failures := make(chan yopay.PaymentFailureNotificationResponse, 1)
+	srv := httptest.NewServer(&yopay.NotificationHandler{
+		API: api,
+		OnFailure: func(ctx context.Context, n yopay.PaymentFailureNotificationResponse) error {
+			failures <- n
+			return nil
+		},
+	})
+	defer srv.Close()
+
+	g.AddScenario(Scenario{Account: "256771111111", States: []Transition{{50 * time.Millisecond, yopay.StateFailed}}})
+	d, err := api.DepositFundsContext(context.Background(), yopay.DepositFundsRequest{
+		Account: "256771111111", Amount: yopay.Money{Minor: 100000}, Narrative: "deposit", FailureNotificationUrl: srv.URL,
+	})
+	if err != nil || d.TransactionStatus != yopay.StatePending {
+		t.Fatalf("deposit: %+v %v", d, err)
+	}
+	select {
+	case n := <-failures:
+		if n.FailedTransactionReference != d.TransactionReference {
+			t.Fatalf("failure notification for %s", n.FailedTransactionReference)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no failure notification")
+	}
+	s, err := api.CheckTransactionStatus(d.TransactionReference, "")
+	if err != nil || s.TransactionStatus != yopay.StateFailed || g.Balance(MobileMoneyCurrency) != 0 {
+		t.Fatalf("status: %+v %v", s, err)
+	}
+
+	g.AddScenario(Scenario{Account: "256772222222", States: []Transition{{0, yopay.StateIndeterminate}}})
+	d, err = api.DepositFunds("256772222222", 1000, "deposit")
+	if err != nil || d.TransactionStatus != yopay.StateIndeterminate {
+		t.Fatalf("deposit: %+v %v", d, err)
+	}
+	if s, _ := api.CheckTransactionStatus(d.TransactionReference, ""); s.TransactionStatus != yopay.StateIndeterminate {
+		t.Fatalf("status %s", s.TransactionStatus)
+	}
+}
+
+func TestScenarioResponses(t *testing.T) {
+	api, g := newGatewayApi(t)
+	g.SetBalance(MobileMoneyCurrency, 1000)
+
+	g.AddScenario(Scenario{Method: "acwithdrawfunds", Err: yopay.ErrInsufficientBalance, Times: 1})
+	if _, err := api.WithdrawFunds("256771234567", 10, "withdraw"); !errors.Is(err, yopay.ErrInsufficientBalance) {
+		t.Fatalf("scenario error not reported: %v", err)
+	}
+	if _, err := api.WithdrawFunds("256771234567", 10, "withdraw"); err != nil {
+		t.Fatalf("scenario not used up: %v", err)
+	}
+
+	g.AddScenario(Scenario{Method: "acacctbalance", HTTPStatus: http.StatusInternalServerError, Times: 1})
+	if _, err := api.GetAcctBalance(); err == nil {
+		t.Fatal("HTTP 500 not reported")
+	}
+	g.AddScenario(Scenario{Method: "acacctbalance", Truncate: true, Times: 1})
+	if _, err := api.GetAcctBalance(); err == nil {
+		t.Fatal("truncated response not reported")
+	}
+	g.AddScenario(Scenario{Method: "acacctbalance", Delay: time.Second, Times: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := api.GetAcctBalanceContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("slow response not timed out: %v", err)
+	}
+
+	// the withdrawal is recorded although the connection drops, the client resolves it by the reference
+	api.ResolveTimeouts = true
+	api.AutoExternalReference = true
+	g.AddScenario(Scenario{Method: "acwithdrawfunds", Drop: true, Times: 1})
+	w, err := api.WithdrawFunds("256771234567", 100, "withdraw")
+	if err != nil || w.TransactionStatus != yopay.StateSucceeded || g.Balance(MobileMoneyCurrency) != 890 {
+		t.Fatalf("dropped withdrawal not resolved: %+v %v", w, err)
+	}
+
+	g.ClearScenarios()
+	if _, err := api.GetAcctBalance(); err != nil {
+		t.Fatal(err)
+	}
+}
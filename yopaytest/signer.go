@@ -0,0 +1,110 @@
+// yopaytest project signer.go
+package yopaytest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/voyager3m/yopay"
+)
+
+/* Signer
+Throwaway key pair signing notifications the way the Yo! gateway does
+*/
+type Signer struct {
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+	// the certificate in PEM format, e.g. for yopay.LoadKeysFile
+	CertificatePEM []byte
+}
+
+/* NewSigner
+Generate a new RSA key with a self-signed certificate valid for a day around now
+*/
+func NewSigner() (*Signer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano()),
+		Subject:      pkix.Name{CommonName: "yopaytest"},
+		NotBefore:    now.Add(-12 * time.Hour),
+		NotAfter:     now.Add(12 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{
+		Key:            key,
+		Certificate:    cert,
+		CertificatePEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}, nil
+}
+
+/* Keys
+Key set trusting the signer certificate
+*/
+func (s *Signer) Keys() yopay.KeySet {
+	return yopay.KeySet{{PublicKey: &s.Key.PublicKey, Certificate: s.Certificate}}
+}
+
+/* Trust
+Make api accept notifications signed by s, replacing the keys trusted before
+*/
+func (s *Signer) Trust(api *yopay.YoAPI) {
+	api.NotificationKeys = s.Keys()
+}
+
+/* Sign
+Base64 SHA1 with RSA signature of data
+*/
+func (s *Signer) Sign(data string) string {
+	digest := sha1.Sum([]byte(data))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.Key, crypto.SHA1, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+/* PaymentForm
+Signed instant payment notification form as the gateway posts it to InstantNotificationUrl
+*/
+func (s *Signer) PaymentForm(n yopay.PaymentNotificationResponse) url.Values {
+	return url.Values{
+		"date_time":    {n.DateTime},
+		"amount":       {n.Amount},
+		"narrative":    {n.Narrative},
+		"network_ref":  {n.NetworkRef},
+		"external_ref": {n.ExternalRef},
+		"msisdn":       {n.Msisdn},
+		"signature":    {s.Sign(n.DateTime + n.Amount + n.Narrative + n.NetworkRef + n.ExternalRef + n.Msisdn)},
+	}
+}
+
+/* FailureForm
+Signed failure notification form as the gateway posts it to FailureNotificationUrl
+*/
+func (s *Signer) FailureForm(n yopay.PaymentFailureNotificationResponse) url.Values {
+	return url.Values{
+		"failed_transaction_reference": {n.FailedTransactionReference},
+		"transaction_init_date":        {n.TransactionInitDate},
+		"verification":                 {s.Sign(n.FailedTransactionReference + n.TransactionInitDate)},
+	}
+}
@@ -0,0 +1,62 @@
+package yopaytest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/voyager3m/yopay"
+)
+
+func TestSignerNotifications(t *testing.T) {
+	signer, err := NewSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := yopay.NewYoApi("user", "password")
+	api.CheckKeyValidity = true
+	signer.Trust(&api)
+
+	var payments, failures int
+	srv := httptest.NewServer(&yopay.NotificationHandler{
+		API: &api,
+		OnPayment: func(ctx context.Context, n yopay.PaymentNotificationResponse) error {
+			payments++
+			return nil
+		},
+		OnFailure: func(ctx context.Context, n yopay.PaymentFailureNotificationResponse) error {
+			failures++
+			return nil
+		},
+	})
+	defer srv.Close()
+
+	resp, err := http.PostForm(srv.URL, signer.PaymentForm(yopay.PaymentNotificationResponse{
+		DateTime:    "2019-09-04 10:00:00",
+		Amount:      "1000",
+		Narrative:   "test & co",
+		NetworkRef:  "1234",
+		ExternalRef: "inv-1",
+		Msisdn:      "256771234567",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || payments != 1 {
+		t.Fatalf("signed payment rejected: %d", resp.StatusCode)
+	}
+
+	resp, err = http.PostForm(srv.URL, signer.FailureForm(yopay.PaymentFailureNotificationResponse{
+		FailedTransactionReference: "1234",
+		TransactionInitDate:        "2019-09-04 10:00:00",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || failures != 1 {
+		t.Fatalf("signed failure rejected: %d", resp.StatusCode)
+	}
+}